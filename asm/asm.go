@@ -0,0 +1,638 @@
+// Package asm assembles 6502 source into machine code. Opcodes, addressing
+// modes and sizes all come from a cpu.InstructionTable, so it assembles
+// exactly the instructions the CPU can execute
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/expr"
+)
+
+// Error is an error in a line of source
+type Error struct {
+	Line int // starting at 1
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Segment is code assembled at consecutive addresses
+type Segment struct {
+	Origin uint16
+	Bytes  []uint8
+}
+
+// Program is the result of assembling source
+type Program struct {
+	Segments []Segment
+	Labels   map[string]uint16 // local labels are named global@local
+}
+
+// Origin returns the lowest address of the program
+func (p *Program) Origin() (origin uint16) {
+	origin = 0xffff
+	for _, segment := range p.Segments {
+		if segment.Origin < origin {
+			origin = segment.Origin
+		}
+	}
+	if len(p.Segments) == 0 {
+		origin = 0
+	}
+	return
+}
+
+// Bytes returns the program as one block starting at Origin, with zeros
+// in between segments
+func (p *Program) Bytes() (result []uint8) {
+	origin := int(p.Origin())
+	for _, segment := range p.Segments {
+		start := int(segment.Origin) - origin
+		if end := start + len(segment.Bytes); end > len(result) {
+			result = append(result, make([]uint8, end-len(result))...)
+		}
+		copy(result[start:], segment.Bytes)
+	}
+	return
+}
+
+// Load writes every segment of the program to mem with Poke, so that it can
+// be loaded into read only memory
+func (p *Program) Load(mem cpu.Memory) {
+	for _, segment := range p.Segments {
+		for i, value := range segment.Bytes {
+			cpu.Poke(mem, segment.Origin+uint16(i), value)
+		}
+	}
+}
+
+// Assembler turns source into a Program using the opcodes of an
+// InstructionTable
+type Assembler struct {
+	opcodes map[string]map[cpu.AddressingMode]cpu.OpCode
+}
+
+// New returns an Assembler for the instructions in table. Where several
+// opcodes share a mneumonic and addressing mode, as the unofficial NOPs do,
+// the documented one or else the lowest is used
+func New(table cpu.InstructionTable) *Assembler {
+	a := &Assembler{opcodes: make(map[string]map[cpu.AddressingMode]cpu.OpCode)}
+	for o := 0xff; o >= 0; o-- {
+		inst := table.Lookup(cpu.OpCode(o))
+		if inst == nil {
+			continue
+		}
+
+		modes, ok := a.opcodes[inst.Mneumonic]
+		if !ok {
+			modes = make(map[cpu.AddressingMode]cpu.OpCode)
+			a.opcodes[inst.Mneumonic] = modes
+		}
+
+		if previous, ok := modes[inst.Mode]; ok && inst.Illegal && !table.Lookup(previous).Illegal {
+			continue
+		}
+		modes[inst.Mode] = inst.OpCode
+	}
+	return a
+}
+
+// Assemble assembles source for the NMOS 6502 including the unofficial
+// opcodes
+func Assemble(source string) (*Program, error) {
+	table := cpu.NewInstructionTable()
+	table.InitInstructions()
+	table.InitIllegalInstructions()
+	return New(table).Assemble(source)
+}
+
+// line is a parsed line of source
+type line struct {
+	number    int
+	label     string
+	constant  string // name = operand
+	mneumonic string // or directive, in upper case
+	operand   string
+}
+
+// assembly is the state of assembling one Program
+type assembly struct {
+	*Assembler
+	lines   []line
+	symbols map[string]int
+	modes   map[int]cpu.AddressingMode // chosen for each line in the first pass
+	scope   string                     // last global label
+	pc      uint16
+	final   bool
+	program *Program
+}
+
+// Assemble assembles source. Each line holds an optional label followed by
+// a colon, and an instruction or a directive, or defines a constant with
+// name = expression. Comments start with ;. Labels starting with @ are
+// local to the previous global label. The directives are .org address,
+// .byte and .word with lists of expressions, .byte also takes "strings",
+// and .res count[, fill]
+func (a *Assembler) Assemble(source string) (*Program, error) {
+	as := &assembly{
+		Assembler: a,
+		symbols:   make(map[string]int),
+		modes:     make(map[int]cpu.AddressingMode),
+	}
+
+	for i, text := range strings.Split(source, "\n") {
+		as.lines = append(as.lines, parseLine(i+1, text))
+	}
+
+	// the first pass finds the address of every label, the second one
+	// generates code with all of them known
+	for _, final := range []bool{false, true} {
+		as.final = final
+		as.pc = 0
+		as.scope = ""
+		as.program = &Program{Labels: make(map[string]uint16)}
+		for _, l := range as.lines {
+			if err := as.assemble(l); err != nil {
+				return nil, &Error{l.number, err}
+			}
+		}
+	}
+
+	for name, value := range as.symbols {
+		as.program.Labels[name] = uint16(value)
+	}
+	return as.program, nil
+}
+
+func parseLine(number int, text string) (l line) {
+	l.number = number
+	text = strings.TrimSpace(stripComment(text))
+
+	if i := strings.Index(text, ":"); i > 0 && isSymbol(text[:i]) {
+		l.label = text[:i]
+		text = strings.TrimSpace(text[i+1:])
+	}
+
+	if i := strings.Index(text, "="); i > 0 && isSymbol(strings.TrimSpace(text[:i])) {
+		l.constant = strings.TrimSpace(text[:i])
+		l.operand = strings.TrimSpace(text[i+1:])
+		return
+	}
+
+	if text == "" {
+		return
+	}
+
+	if i := strings.IndexAny(text, " \t"); i > 0 {
+		l.mneumonic = text[:i]
+		l.operand = strings.TrimSpace(text[i+1:])
+	} else {
+		l.mneumonic = text
+	}
+	l.mneumonic = strings.ToUpper(l.mneumonic)
+	return
+}
+
+// stripComment removes a ; comment that is not in a string or character
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func isSymbol(text string) bool {
+	if text == "" || !isSymbolStart(text[0]) {
+		return false
+	}
+	for i := 1; i < len(text); i++ {
+		if !isSymbolPart(text[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSymbolStart(c byte) bool {
+	return c == '_' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSymbolPart(c byte) bool {
+	return isSymbolStart(c) || (c >= '0' && c <= '9')
+}
+
+// name qualifies local labels with the global label they belong to
+func (as *assembly) name(symbol string) string {
+	if strings.HasPrefix(symbol, "@") {
+		return as.scope + symbol
+	}
+	return symbol
+}
+
+func (as *assembly) define(symbol string, value int) error {
+	name := as.name(symbol)
+	if _, ok := as.symbols[name]; ok && !as.final {
+		return fmt.Errorf("%v is already defined", symbol)
+	}
+	as.symbols[name] = value
+	return nil
+}
+
+// evaluate returns the value of text. In the first pass undefined symbols
+// give an expr.UndefinedError
+func (as *assembly) evaluate(text string) (int, error) {
+	e, err := expr.Parse(text)
+	if err != nil {
+		return 0, err
+	}
+	return e.Eval(as)
+}
+
+// Symbol, PC and Read make the assembly the expr.Env of its operands
+
+func (as *assembly) Symbol(name string) (int, error) {
+	if value, ok := as.symbols[as.name(name)]; ok {
+		return value, nil
+	}
+	return 0, expr.UndefinedError(name)
+}
+
+func (as *assembly) PC() int {
+	return int(as.pc)
+}
+
+func (as *assembly) Read(address uint16) (uint8, error) {
+	return 0, errors.New("memory can not be read while assembling")
+}
+
+func (as *assembly) emit(values ...uint8) {
+	segments := as.program.Segments
+	if n := len(segments); n == 0 || int(segments[n-1].Origin)+len(segments[n-1].Bytes) != int(as.pc) {
+		as.program.Segments = append(segments, Segment{Origin: as.pc})
+	}
+	segment := &as.program.Segments[len(as.program.Segments)-1]
+	segment.Bytes = append(segment.Bytes, values...)
+	as.pc += uint16(len(values))
+}
+
+func (as *assembly) assemble(l line) (err error) {
+	if l.label != "" {
+		if !strings.HasPrefix(l.label, "@") {
+			as.scope = l.label
+		}
+		if err = as.define(l.label, int(as.pc)); err != nil {
+			return
+		}
+	}
+
+	if l.constant != "" {
+		value, err := as.evaluate(l.operand)
+		if _, ok := err.(expr.UndefinedError); ok && !as.final {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return as.define(l.constant, value)
+	}
+
+	switch l.mneumonic {
+	case "":
+		return
+	case ".ORG":
+		return as.org(l.operand)
+	case ".BYTE":
+		return as.data(l.operand, 1)
+	case ".WORD":
+		return as.data(l.operand, 2)
+	case ".RES":
+		return as.res(l.operand)
+	}
+
+	return as.instruction(l)
+}
+
+func (as *assembly) org(operand string) error {
+	value, err := as.evaluate(operand)
+	if err != nil {
+		// the origin has to be known in the first pass
+		return err
+	}
+	if value < 0 || value > 0xffff {
+		return fmt.Errorf("origin %#x out of range", value)
+	}
+	as.pc = uint16(value)
+	return nil
+}
+
+// data emits a list of bytes or words
+func (as *assembly) data(operand string, size int) error {
+	for _, item := range splitList(operand) {
+		if size == 1 && len(item) >= 2 && item[0] == '"' && item[len(item)-1] == '"' {
+			as.emit([]uint8(item[1 : len(item)-1])...)
+			continue
+		}
+
+		value, err := as.value(item)
+		if err != nil {
+			return err
+		}
+
+		if size == 1 {
+			if value < -0x80 || value > 0xff {
+				return fmt.Errorf("byte %v out of range", item)
+			}
+			as.emit(uint8(value))
+		} else {
+			if value < -0x8000 || value > 0xffff {
+				return fmt.Errorf("word %v out of range", item)
+			}
+			as.emit(uint8(value), uint8(value>>8))
+		}
+	}
+	return nil
+}
+
+// res reserves count bytes set to fill or 0
+func (as *assembly) res(operand string) error {
+	items := splitList(operand)
+	if len(items) == 0 || len(items) > 2 {
+		return fmt.Errorf(".res takes a count and an optional fill value")
+	}
+
+	count, err := as.evaluate(items[0])
+	if err != nil {
+		return err
+	}
+	if count < 0 || int(as.pc)+count > 0x10000 {
+		return fmt.Errorf(".res count %v out of range", count)
+	}
+
+	var fill int
+	if len(items) == 2 {
+		if fill, err = as.value(items[1]); err != nil {
+			return err
+		}
+	}
+
+	as.emit(make([]uint8, count)...)
+	segment := as.program.Segments[len(as.program.Segments)-1].Bytes
+	for i := len(segment) - count; i < len(segment); i++ {
+		segment[i] = uint8(fill)
+	}
+	return nil
+}
+
+// value evaluates text, which may be undefined until the final pass
+func (as *assembly) value(text string) (int, error) {
+	value, err := as.evaluate(text)
+	if _, ok := err.(expr.UndefinedError); ok && !as.final {
+		return 0, nil
+	}
+	return value, err
+}
+
+// splitList splits a comma separated list, except where commas are quoted
+// or in parentheses
+func splitList(text string) (items []string) {
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return
+}
+
+// operand syntaxes, each of which maps to one or more addressing modes
+const (
+	syntaxNone      = iota // implied or accumulator
+	syntaxA                // A
+	syntaxImmediate        // #expr
+	syntaxPlain            // expr
+	syntaxX                // expr,X
+	syntaxY                // expr,Y
+	syntaxIndirect         // (expr)
+	syntaxIndirectX        // (expr,X)
+	syntaxIndirectY        // (expr),Y
+)
+
+// parseOperand returns the syntax of operand and its expression
+func parseOperand(operand string) (syntax int, expression string) {
+	upper := strings.ToUpper(operand)
+	trim := func(text string) string {
+		return strings.TrimSpace(text)
+	}
+
+	switch {
+	case operand == "":
+		return syntaxNone, ""
+	case upper == "A":
+		return syntaxA, ""
+	case strings.HasPrefix(operand, "#"):
+		return syntaxImmediate, trim(operand[1:])
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(strings.Replace(upper, " ", "", -1), ",X)"):
+		return syntaxIndirectX, trim(operand[1:strings.LastIndex(operand, ",")])
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(strings.Replace(upper, " ", "", -1), "),Y"):
+		return syntaxIndirectY, trim(operand[1:strings.LastIndex(operand, ")")])
+	case strings.HasPrefix(operand, "(") && strings.HasSuffix(operand, ")") && closes(operand) == len(operand)-1:
+		return syntaxIndirect, trim(operand[1 : len(operand)-1])
+	}
+
+	if i := strings.LastIndex(operand, ","); i > 0 {
+		switch trim(upper[i+1:]) {
+		case "X":
+			return syntaxX, trim(operand[:i])
+		case "Y":
+			return syntaxY, trim(operand[:i])
+		}
+	}
+	return syntaxPlain, operand
+}
+
+// closes returns the index of the parenthesis that closes the one text
+// starts with
+func closes(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// candidates returns the addressing modes for syntax in order of preference,
+// the zero page ones first
+func candidates(syntax int) []cpu.AddressingMode {
+	switch syntax {
+	case syntaxNone:
+		return []cpu.AddressingMode{cpu.ModeImplied, cpu.ModeAccumulator}
+	case syntaxA:
+		return []cpu.AddressingMode{cpu.ModeAccumulator}
+	case syntaxImmediate:
+		return []cpu.AddressingMode{cpu.ModeImmediate}
+	case syntaxPlain:
+		return []cpu.AddressingMode{cpu.ModeRelative, cpu.ModeZeroPage, cpu.ModeAbsolute}
+	case syntaxX:
+		return []cpu.AddressingMode{cpu.ModeZeroPageX, cpu.ModeAbsoluteX}
+	case syntaxY:
+		return []cpu.AddressingMode{cpu.ModeZeroPageY, cpu.ModeAbsoluteY}
+	case syntaxIndirect:
+		return []cpu.AddressingMode{cpu.ModeIndirect, cpu.ModeZeroPageIndirect}
+	case syntaxIndirectX:
+		return []cpu.AddressingMode{cpu.ModeIndexedIndirect, cpu.ModeAbsoluteIndexedIndirect}
+	case syntaxIndirectY:
+		return []cpu.AddressingMode{cpu.ModeIndirectIndexed}
+	}
+	return nil
+}
+
+func isZeroPage(mode cpu.AddressingMode) bool {
+	switch mode {
+	case cpu.ModeZeroPage, cpu.ModeZeroPageX, cpu.ModeZeroPageY,
+		cpu.ModeIndexedIndirect, cpu.ModeIndirectIndexed, cpu.ModeZeroPageIndirect:
+		return true
+	}
+	return false
+}
+
+// mode picks the addressing mode of an instruction in the first pass. Zero
+// page modes are only picked if the operand is already known to fit
+func (as *assembly) mode(l line, modes map[cpu.AddressingMode]cpu.OpCode) (mode cpu.AddressingMode, err error) {
+	syntax, expression := parseOperand(l.operand)
+
+	// (expr) is an expression in parentheses for instructions without an
+	// indirect mode
+	if syntax == syntaxIndirect {
+		_, indirect := modes[cpu.ModeIndirect]
+		_, zeroPageIndirect := modes[cpu.ModeZeroPageIndirect]
+		if !indirect && !zeroPageIndirect {
+			syntax, expression = syntaxPlain, l.operand
+		}
+	}
+
+	var value int
+	known := false
+	if expression != "" {
+		value, err = as.evaluate(expression)
+		if _, ok := err.(expr.UndefinedError); ok {
+			err = nil
+		} else if err != nil {
+			return
+		} else {
+			known = true
+		}
+	}
+
+	var found []cpu.AddressingMode
+	for _, mode := range candidates(syntax) {
+		if _, ok := modes[mode]; ok {
+			found = append(found, mode)
+		}
+	}
+	if len(found) == 0 {
+		return 0, fmt.Errorf("%v does not take operand %q", l.mneumonic, l.operand)
+	}
+
+	// use the zero page mode if the operand fits or if it is the only one
+	for _, mode := range found {
+		if !isZeroPage(mode) || len(found) == 1 || (known && value >= 0 && value <= 0xff) {
+			return mode, nil
+		}
+	}
+	return found[len(found)-1], nil
+}
+
+func (as *assembly) instruction(l line) error {
+	modes, ok := as.opcodes[l.mneumonic]
+	if !ok {
+		return fmt.Errorf("unknown instruction %v", l.mneumonic)
+	}
+
+	// the size of every instruction is fixed in the first pass, so that
+	// labels keep their addresses in the second one
+	if !as.final {
+		mode, err := as.mode(l, modes)
+		if err != nil {
+			return err
+		}
+		as.modes[l.number] = mode
+	}
+	mode := as.modes[l.number]
+	opcode := modes[mode]
+
+	syntax, expression := parseOperand(l.operand)
+	if syntax == syntaxIndirect && mode != cpu.ModeIndirect && mode != cpu.ModeZeroPageIndirect {
+		expression = l.operand
+	}
+
+	if expression == "" {
+		as.emit(uint8(opcode))
+		return nil
+	}
+
+	value, err := as.value(expression)
+	if err != nil {
+		return err
+	}
+
+	pc := as.pc
+	switch {
+	case mode == cpu.ModeRelative:
+		offset := value - (int(pc) + 2)
+		if as.final && (offset < -0x80 || offset > 0x7f) {
+			return fmt.Errorf("branch to $%04X out of range", value)
+		}
+		as.emit(uint8(opcode), uint8(offset))
+	case mode.Operands() == 1:
+		if as.final && (value < -0x80 || value > 0xff || (isZeroPage(mode) && value < 0)) {
+			return fmt.Errorf("operand %v out of range", expression)
+		}
+		as.emit(uint8(opcode), uint8(value))
+	default:
+		if as.final && (value < 0 || value > 0xffff) {
+			return fmt.Errorf("operand %v out of range", expression)
+		}
+		as.emit(uint8(opcode), uint8(value), uint8(value>>8))
+	}
+	return nil
+}
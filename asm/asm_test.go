@@ -0,0 +1,188 @@
+package asm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mpicard/gones"
+)
+
+func TestAssembleAddressingModes(t *testing.T) {
+	for _, a := range []struct {
+		source string
+		bytes  []uint8
+	}{
+		{"NOP", []uint8{0xea}},
+		{"ASL", []uint8{0x0a}},
+		{"asl a", []uint8{0x0a}},
+		{"LDA #$ff", []uint8{0xa9, 0xff}},
+		{"LDA #-1", []uint8{0xa9, 0xff}},
+		{"LDA $12", []uint8{0xa5, 0x12}},
+		{"LDA $12,X", []uint8{0xb5, 0x12}},
+		{"LDX $12,Y", []uint8{0xb6, 0x12}},
+		{"LDA $12,Y", []uint8{0xb9, 0x12, 0x00}},
+		{"LDA $1234", []uint8{0xad, 0x34, 0x12}},
+		{"LDA $1234,X", []uint8{0xbd, 0x34, 0x12}},
+		{"LDA $1234, y", []uint8{0xb9, 0x34, 0x12}},
+		{"JMP ($1234)", []uint8{0x6c, 0x34, 0x12}},
+		{"LDA ($12,X)", []uint8{0xa1, 0x12}},
+		{"LDA ($12),Y", []uint8{0xb1, 0x12}},
+		{"LDA ($10+2)*2", []uint8{0xa5, 0x24}},
+		{"LDA #<$1234", []uint8{0xa9, 0x34}},
+		{"LDA #>$1234", []uint8{0xa9, 0x12}},
+		{"LDA #%1010 | 1 << 4", []uint8{0xa9, 0x1a}},
+		{"LDA #'A'", []uint8{0xa9, 0x41}},
+		{"BNE *", []uint8{0xd0, 0xfe}},
+		{"LAX $12", []uint8{0xa7, 0x12}},
+		{"SBC #1", []uint8{0xe9, 0x01}},
+		{"NOP $12", []uint8{0x04, 0x12}},
+	} {
+		program, err := Assemble(a.source)
+		if err != nil {
+			t.Errorf("%v: %v", a.source, err)
+			continue
+		}
+
+		if result := program.Bytes(); !bytes.Equal(result, a.bytes) {
+			t.Errorf("%v assembled to % x not % x", a.source, result, a.bytes)
+		}
+	}
+}
+
+func TestAssembleLabels(t *testing.T) {
+	program, err := Assemble(`
+		.org $0200
+count = 3
+start:	LDX #count	; forward references to zero page
+		LDA data,X
+		STA result
+@loop:	DEX
+		BNE @loop
+		JMP end
+other:	BEQ @loop	; a different @loop
+@loop:	RTS
+end:	JMP start
+data:	.byte 1, 2, "ab"
+		.word end, $1234
+		.res 2, $ff
+result:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []uint8{
+		0xa2, 0x03,
+		0xbd, 0x14, 0x02,
+		0x8d, 0x1e, 0x02,
+		0xca,
+		0xd0, 0xfd,
+		0x4c, 0x11, 0x02,
+		0xf0, 0x00,
+		0x60,
+		0x4c, 0x00, 0x02,
+		0x01, 0x02, 'a', 'b',
+		0x11, 0x02, 0x34, 0x12,
+		0xff, 0xff,
+	}
+	if result := program.Bytes(); !bytes.Equal(result, expected) {
+		t.Errorf("Assembled\n% x\nnot\n% x", result, expected)
+	}
+
+	if program.Origin() != 0x0200 {
+		t.Errorf("Origin %#04x != 0x0200", program.Origin())
+	}
+
+	if program.Labels["start@loop"] != 0x0208 || program.Labels["other@loop"] != 0x0210 {
+		t.Errorf("Local labels %v", program.Labels)
+	}
+}
+
+func TestAssembleSegments(t *testing.T) {
+	program, err := Assemble(`
+		.org $fffc
+		.word reset
+		.org $8000
+reset:	JMP reset
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem := cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE)
+	program.Load(mem)
+
+	if mem.Read(0xfffc) != 0x00 || mem.Read(0xfffd) != 0x80 || mem.Read(0x8000) != 0x4c {
+		t.Error("Program was not loaded")
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, source := range []string{
+		"FOO",
+		"LDA",
+		"LDA undefined",
+		"STA #1",
+		"LDA #$100",
+		"start: NOP\nstart: NOP",
+		"BNE $1000",
+		"LDA ($12",
+		".org later\nlater:",
+	} {
+		_, err := Assemble(source)
+		if err == nil {
+			t.Errorf("No error for %q", source)
+			continue
+		}
+
+		if _, ok := err.(*Error); !ok {
+			t.Errorf("%q returned %T not *Error", source, err)
+		}
+	}
+}
+
+func TestAssembleCMOS(t *testing.T) {
+	c := cpu.NewCPU(nil, cpu.WithVariant(cpu.CMOS65C02))
+
+	program, err := New(c.Instructions).Assemble(`
+		LDA ($12)
+		JMP ($1234,X)
+		STZ $12
+		BRA *
+		PHX
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []uint8{0xb2, 0x12, 0x7c, 0x34, 0x12, 0x64, 0x12, 0x80, 0xfe, 0xda}
+	if result := program.Bytes(); !bytes.Equal(result, expected) {
+		t.Errorf("Assembled % x not % x", result, expected)
+	}
+}
+
+func TestAssembleRuns(t *testing.T) {
+	program, err := Assemble(`
+		.org $0200
+		LDA #$40
+		CLC
+		ADC #$02
+		STA $10
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cpu.NewCPU(cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE))
+	program.Load(c.Memory)
+	c.Registers.PC = 0x0200
+	for i := 0; i < 4; i++ {
+		if _, err = c.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c.Memory.Read(0x0010) != 0x42 {
+		t.Errorf("Memory at 0x10 0x42 != %#02x", c.Memory.Read(0x0010))
+	}
+}
@@ -0,0 +1,160 @@
+package cpu
+
+import "fmt"
+
+// Mapping places a device on a Bus from Start to End inclusive. The address
+// the device sees is the CPU address and Mask, so that a Mask of 0x07ff
+// mirrors 2 KiB of RAM over 0x0000-0x1fff and one of 0x2007 mirrors the 8
+// PPU registers over 0x2000-0x3fff. A Mask of 0 passes addresses unchanged.
+// Where mappings overlap the one with the highest Priority wins, and of
+// equal priorities the one mapped last. A nil Device leaves the range
+// unmapped, hiding lower priority mappings
+type Mapping struct {
+	Name     string
+	Start    uint16
+	End      uint16
+	Mask     uint16
+	Priority int
+	Device   Memory
+}
+
+// OpenBusReader is implemented by devices that leave some data lines
+// undriven when read, such as the controller ports at 0x4016 and 0x4017
+// which only drive the low 5 bits. ReadOpenBus is passed the value left on
+// the bus and returns it with the driven bits replaced
+type OpenBusReader interface {
+	ReadOpenBus(address uint16, bus uint8) (value uint8)
+}
+
+// Bus is a Memory that routes reads and writes to the devices mapped into
+// it. Like the NES data bus it holds the last value read or written, which
+// is what reads from unmapped addresses return. Writes to unmapped
+// addresses are ignored
+type Bus struct {
+	mappings []*Mapping
+	decode   []*Mapping // by address, nil where unmapped
+	last     uint8
+	pullUp   bool
+	unmapped uint8
+}
+
+// BusOption configures a Bus
+type BusOption func(*Bus)
+
+// WithUnmappedValue makes reads from unmapped addresses, and the bits
+// OpenBusReader devices leave undriven, return value instead of the open
+// bus, e.g. 0xff as if the data lines were pulled up
+func WithUnmappedValue(value uint8) BusOption {
+	return func(bus *Bus) {
+		bus.pullUp = true
+		bus.unmapped = value
+	}
+}
+
+// NewBus creates a Bus with nothing mapped
+func NewBus(options ...BusOption) *Bus {
+	bus := &Bus{decode: make([]*Mapping, 0x10000)}
+	for _, option := range options {
+		option(bus)
+	}
+	return bus
+}
+
+// Map adds a mapping to the bus
+func (bus *Bus) Map(m Mapping) error {
+	if m.End < m.Start {
+		return fmt.Errorf("mapping %q ends at %#04x before it starts at %#04x", m.Name, m.End, m.Start)
+	}
+	if m.Mask == 0 {
+		m.Mask = 0xffff
+	}
+
+	bus.mappings = append(bus.mappings, &m)
+	for address := int(m.Start); address <= int(m.End); address++ {
+		if d := bus.decode[address]; d == nil || d.Priority <= m.Priority {
+			bus.decode[address] = &m
+		}
+	}
+	return nil
+}
+
+// Mappings returns the mappings in the order they were added
+func (bus *Bus) Mappings() []Mapping {
+	result := make([]Mapping, len(bus.mappings))
+	for i, m := range bus.mappings {
+		result[i] = *m
+	}
+	return result
+}
+
+// Lookup returns the mapping that decodes address, which is false if the
+// address is unmapped
+func (bus *Bus) Lookup(address uint16) (m Mapping, ok bool) {
+	if d := bus.decode[address]; d != nil && d.Device != nil {
+		return *d, true
+	}
+	return
+}
+
+// Reset resets every mapped device once
+func (bus *Bus) Reset() {
+	reset := make(map[Memory]bool)
+	for _, m := range bus.mappings {
+		if m.Device != nil && !reset[m.Device] {
+			reset[m.Device] = true
+			m.Device.Reset()
+		}
+	}
+}
+
+// OpenBus returns the value a read from an unmapped address would return
+func (bus *Bus) OpenBus() uint8 {
+	if bus.pullUp {
+		return bus.unmapped
+	}
+	return bus.last
+}
+
+func (bus *Bus) Read(address uint16) (value uint8) {
+	if m := bus.decode[address]; m == nil || m.Device == nil {
+		value = bus.OpenBus()
+	} else if r, ok := m.Device.(OpenBusReader); ok {
+		value = r.ReadOpenBus(address&m.Mask, bus.OpenBus())
+	} else {
+		value = m.Device.Read(address & m.Mask)
+	}
+	bus.last = value
+	return
+}
+
+func (bus *Bus) Write(address uint16, value uint8) (oldValue uint8) {
+	bus.last = value
+	if m := bus.decode[address]; m != nil && m.Device != nil {
+		return m.Device.Write(address&m.Mask, value)
+	}
+	return
+}
+
+// Peek reads address from its device with Peek, leaving the open bus as it
+// was. Unmapped addresses and devices that are not a DebugMemory return
+// the open bus
+func (bus *Bus) Peek(address uint16) (value uint8) {
+	if m := bus.decode[address]; m != nil {
+		if d, ok := m.Device.(DebugMemory); ok {
+			return d.Peek(address & m.Mask)
+		}
+	}
+	return bus.OpenBus()
+}
+
+// Poke writes address to its device with Poke, leaving the open bus as it
+// was. Pokes to unmapped addresses and devices that are not a DebugMemory
+// are ignored
+func (bus *Bus) Poke(address uint16, value uint8) (oldValue uint8) {
+	if m := bus.decode[address]; m != nil {
+		if d, ok := m.Device.(DebugMemory); ok {
+			return d.Poke(address&m.Mask, value)
+		}
+	}
+	return
+}
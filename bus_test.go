@@ -0,0 +1,217 @@
+package cpu
+
+import "testing"
+
+// registerDevice is a device that counts its resets and remembers the
+// addresses it saw, except when peeked and poked
+type registerDevice struct {
+	BasicMemory
+	resets  int
+	address uint16
+}
+
+func (r *registerDevice) Reset() {
+	r.resets++
+}
+
+func (r *registerDevice) Read(address uint16) uint8 {
+	r.address = address
+	return uint8(address)
+}
+
+func (r *registerDevice) Write(address uint16, value uint8) uint8 {
+	r.address = address
+	return 0
+}
+
+func (r *registerDevice) Peek(address uint16) uint8 {
+	return uint8(address)
+}
+
+func (r *registerDevice) Poke(address uint16, value uint8) uint8 {
+	return 0
+}
+
+func TestBusMirroring(t *testing.T) {
+	ram := NewBasicMemory(0x0800)
+	ppu := &registerDevice{}
+	prg := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+
+	bus := NewBus()
+	for _, m := range []Mapping{
+		{Name: "RAM", Start: 0x0000, End: 0x1fff, Mask: 0x07ff, Device: ram},
+		{Name: "PPU", Start: 0x2000, End: 0x3fff, Mask: 0x2007, Device: ppu},
+		{Name: "PRG", Start: 0x8000, End: 0xffff, Device: prg},
+	} {
+		if err := bus.Map(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bus.Write(0x0012, 0x34)
+	for _, address := range []uint16{0x0012, 0x0812, 0x1012, 0x1812} {
+		if bus.Read(address) != 0x34 {
+			t.Errorf("RAM at %#04x 0x34 != %#02x", address, bus.Read(address))
+		}
+	}
+
+	if bus.Read(0x3ffe) != 0x06 || ppu.address != 0x2006 {
+		t.Errorf("PPU register %#04x read for 0x3ffe", ppu.address)
+	}
+
+	bus.Write(0xc000, 0x4c)
+	if prg.Read(0xc000) != 0x4c {
+		t.Error("PRG was not written at 0xc000")
+	}
+
+	if bus.Read(0x5000) != 0x4c || bus.Write(0x5000, 0x12) != 0 {
+		t.Error("Unmapped address was decoded")
+	}
+	if _, ok := bus.Lookup(0x5000); ok {
+		t.Error("Lookup found a mapping for 0x5000")
+	}
+	if m, ok := bus.Lookup(0x2345); !ok || m.Name != "PPU" {
+		t.Errorf("Lookup 0x2345 returned %q", m.Name)
+	}
+
+	bus.Map(Mapping{Name: "PPU again", Start: 0x2000, End: 0x2007, Device: ppu})
+	bus.Reset()
+	if ppu.resets != 1 || len(bus.Mappings()) != 4 {
+		t.Errorf("Device reset %d times", ppu.resets)
+	}
+}
+
+func TestBusPriority(t *testing.T) {
+	low := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	high := &registerDevice{}
+
+	bus := NewBus()
+	bus.Map(Mapping{Name: "high", Start: 0x4000, End: 0x4017, Priority: 1, Device: high})
+	bus.Map(Mapping{Name: "low", Start: 0x4000, End: 0x5fff, Device: low})
+	bus.Map(Mapping{Name: "hole", Start: 0x4018, End: 0x401f, Device: nil})
+
+	low.Write(0x4010, 0xaa)
+	low.Write(0x4020, 0xbb)
+	if bus.Read(0x4010) != 0x10 {
+		t.Error("Lower priority mapping won")
+	}
+	if bus.Read(0x4020) != 0xbb {
+		t.Error("Mapping was not decoded past the higher priority one")
+	}
+	if bus.Read(0x4018) != 0xbb {
+		t.Error("Unmapped hole was decoded")
+	}
+
+	if err := bus.Map(Mapping{Name: "backwards", Start: 0x2000, End: 0x1fff}); err == nil {
+		t.Error("Mapping ending before it starts was accepted")
+	}
+}
+
+func TestBusCPU(t *testing.T) {
+	ram := NewBasicMemory(0x0800)
+	prg := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	prg.Write(0xfffc, 0x00)
+	prg.Write(0xfffd, 0x80)
+	prg.Write(0x8000, 0xa9) // LDA #$42
+	prg.Write(0x8001, 0x42)
+	prg.Write(0x8002, 0x8d) // STA $0812
+	prg.Write(0x8003, 0x12)
+	prg.Write(0x8004, 0x08)
+
+	bus := NewBus()
+	bus.Map(Mapping{Start: 0x0000, End: 0x1fff, Mask: 0x07ff, Device: ram})
+	bus.Map(Mapping{Start: 0x8000, End: 0xffff, Device: prg})
+
+	cpu := NewCPU(bus)
+	cpu.Registers.PC = 0x8000
+	for i := 0; i < 2; i++ {
+		if _, err := cpu.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ram.Read(0x0012) != 0x42 {
+		t.Errorf("RAM at 0x0012 0x42 != %#02x", ram.Read(0x0012))
+	}
+}
+
+// controller drives the low 5 bits of 0x4016 and 0x4017
+type controller struct {
+	BasicMemory
+}
+
+func (c *controller) ReadOpenBus(address uint16, bus uint8) uint8 {
+	return bus&0xe0 | 0x01
+}
+
+func TestBusOpenBus(t *testing.T) {
+	for _, b := range []struct {
+		options  []BusOption
+		unmapped uint8
+		joypad   uint8
+		written  uint8
+	}{
+		{nil, 0x50, 0x41, 0x12},
+		{[]BusOption{WithUnmappedValue(0xff)}, 0xff, 0xe1, 0xff},
+	} {
+		prg := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+		prg.Write(0x8000, 0xad) // LDA $4016
+		prg.Write(0x8001, 0x16)
+		prg.Write(0x8002, 0x40)
+		prg.Write(0x8003, 0xad) // LDA $5000
+		prg.Write(0x8004, 0x00)
+		prg.Write(0x8005, 0x50)
+
+		bus := NewBus(b.options...)
+		bus.Map(Mapping{Start: 0x4016, End: 0x4017, Device: &controller{}})
+		bus.Map(Mapping{Start: 0x8000, End: 0xffff, Device: prg})
+
+		cpu := NewCPU(bus)
+		cpu.Registers.PC = 0x8000
+		for _, expected := range []uint8{b.joypad, b.unmapped} {
+			if _, err := cpu.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if cpu.Registers.A != expected {
+				t.Errorf("Register A %#02x != %#02x", cpu.Registers.A, expected)
+			}
+		}
+
+		bus.Write(0x5000, 0x12)
+		if value := bus.Read(0x5001); value != b.written {
+			t.Errorf("Read after write %#02x != %#02x", value, b.written)
+		}
+	}
+}
+
+func TestBusPeekPoke(t *testing.T) {
+	ppu := &registerDevice{}
+	rom := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	rom.DisabledWrites()
+
+	bus := NewBus()
+	bus.Map(Mapping{Start: 0x2000, End: 0x3fff, Mask: 0x2007, Device: ppu})
+	bus.Map(Mapping{Start: 0x8000, End: 0xffff, Device: rom})
+
+	bus.Write(0x0000, 0x40)
+	if bus.Peek(0x3ffa) != 0x02 || ppu.address != 0 {
+		t.Errorf("Peek went through Read, address %#04x", ppu.address)
+	}
+
+	bus.Poke(0x8000, 0x4c)
+	bus.Write(0x8001, 0x12)
+	if bus.Peek(0x8000) != 0x4c || bus.Peek(0x8001) != 0x00 {
+		t.Error("Poke did not write read only memory")
+	}
+
+	if bus.Peek(0x5000) != 0x12 || bus.OpenBus() != 0x12 {
+		t.Errorf("Peek changed the open bus to %#02x", bus.OpenBus())
+	}
+
+	apu := &plainMemory{}
+	bus.Map(Mapping{Start: 0x4000, End: 0x4017, Device: apu})
+	bus.Poke(0x4000, 0x30)
+	if bus.Peek(0x4015) != 0x12 || apu.reads != 0 || apu.writes != 0 {
+		t.Errorf("Peek and Poke made %d reads and %d writes to a device without them", apu.reads, apu.writes)
+	}
+}
@@ -0,0 +1,126 @@
+// Package cartridge reads NES cartridges from iNES files
+package cartridge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	headerSize  = 16
+	trainerSize = 512
+	prgBankSize = 0x4000
+	chrBankSize = 0x2000
+)
+
+// ErrNotINES is returned when a file does not start with "NES\x1a"
+var ErrNotINES = errors.New("not an iNES file")
+
+// Mirroring is how the cartridge wires the PPU's nametables
+type Mirroring uint8
+
+const (
+	// Horizontal mirroring, for vertical scrolling
+	Horizontal Mirroring = iota
+	// Vertical mirroring, for horizontal scrolling
+	Vertical
+	// FourScreen nametables, with extra VRAM on the cartridge
+	FourScreen
+)
+
+func (m Mirroring) String() string {
+	switch m {
+	case Horizontal:
+		return "horizontal"
+	case Vertical:
+		return "vertical"
+	case FourScreen:
+		return "four screen"
+	}
+	return fmt.Sprintf("Mirroring(%d)", uint8(m))
+}
+
+// Cartridge is the contents of an iNES file
+type Cartridge struct {
+	Mapper    uint8
+	Mirroring Mirroring
+	Battery   bool    // PRG RAM at 0x6000-0x7fff is battery backed
+	Trainer   []uint8 // 512 bytes loaded at 0x7000, nil if there is none
+	PRG       []uint8 // PRG ROM, a multiple of 16 KiB
+	CHR       []uint8 // CHR ROM, a multiple of 8 KiB, empty if the board has CHR RAM
+}
+
+// HasCHRRAM returns true if the cartridge has no CHR ROM, which means the
+// board has 8 KiB of CHR RAM instead
+func (c *Cartridge) HasCHRRAM() bool {
+	return len(c.CHR) == 0
+}
+
+// Read parses an iNES file from r
+func Read(r io.Reader) (*Cartridge, error) {
+	header := make([]uint8, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading iNES header: %v", err)
+	}
+
+	if string(header[:4]) != "NES\x1a" {
+		return nil, ErrNotINES
+	}
+
+	if header[4] == 0 {
+		return nil, errors.New("iNES header has no PRG ROM")
+	}
+
+	flags6, flags7 := header[6], header[7]
+	// Old tools wrote a signature such as "DiskDude!" from byte 7 onwards,
+	// which is only recognizable by the padding at 12-15 not being zero.
+	// NES 2.0 headers use those bytes, and keep the mapper nibble in 7
+	if flags7&0x0c != 0x08 && (header[12] != 0 || header[13] != 0 || header[14] != 0 || header[15] != 0) {
+		flags7 = 0
+	}
+
+	c := &Cartridge{
+		Mapper:  flags7&0xf0 | flags6>>4,
+		Battery: flags6&0x02 != 0,
+	}
+
+	switch {
+	case flags6&0x08 != 0:
+		c.Mirroring = FourScreen
+	case flags6&0x01 != 0:
+		c.Mirroring = Vertical
+	default:
+		c.Mirroring = Horizontal
+	}
+
+	if flags6&0x04 != 0 {
+		c.Trainer = make([]uint8, trainerSize)
+		if err := readSection(r, "trainer", c.Trainer); err != nil {
+			return nil, err
+		}
+	}
+
+	c.PRG = make([]uint8, int(header[4])*prgBankSize)
+	if err := readSection(r, "PRG ROM", c.PRG); err != nil {
+		return nil, err
+	}
+
+	c.CHR = make([]uint8, int(header[5])*chrBankSize)
+	if err := readSection(r, "CHR ROM", c.CHR); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func readSection(r io.Reader, name string, b []uint8) error {
+	n, err := io.ReadFull(r, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("iNES file is truncated, %s is %d bytes but only %d were read", name, len(b), n)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", name, err)
+	}
+	return nil
+}
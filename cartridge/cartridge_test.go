@@ -0,0 +1,135 @@
+package cartridge
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mpicard/gones"
+)
+
+// rom builds an iNES file with the given header bytes 4 to 7 and section
+// sizes, filling every section with its own value
+func rom(prg, chr, flags6, flags7 uint8, trainer bool) []uint8 {
+	b := append([]uint8("NES\x1a"), prg, chr, flags6, flags7, 0, 0, 0, 0, 0, 0, 0, 0)
+	if trainer {
+		b = append(b, bytes.Repeat([]uint8{0x77}, trainerSize)...)
+	}
+	b = append(b, bytes.Repeat([]uint8{0x88}, int(prg)*prgBankSize)...)
+	return append(b, bytes.Repeat([]uint8{0x99}, int(chr)*chrBankSize)...)
+}
+
+func TestRead(t *testing.T) {
+	for _, r := range []struct {
+		file      []uint8
+		mapper    uint8
+		mirroring Mirroring
+		battery   bool
+		trainer   bool
+		prg, chr  int
+	}{
+		{rom(1, 1, 0x00, 0x00, false), 0, Horizontal, false, false, 0x4000, 0x2000},
+		{rom(2, 0, 0x01, 0x00, false), 0, Vertical, false, false, 0x8000, 0},
+		{rom(8, 2, 0x1a, 0x00, false), 1, FourScreen, true, false, 0x20000, 0x4000},
+		{rom(2, 1, 0x44, 0x40, true), 0x44, Horizontal, false, true, 0x8000, 0x2000},
+	} {
+		c, err := Read(bytes.NewReader(r.file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if c.Mapper != r.mapper || c.Mirroring != r.mirroring || c.Battery != r.battery {
+			t.Errorf("Mapper %d != %d, %v != %v mirroring, battery %v != %v",
+				c.Mapper, r.mapper, c.Mirroring, r.mirroring, c.Battery, r.battery)
+		}
+
+		if (c.Trainer != nil) != r.trainer || len(c.PRG) != r.prg || len(c.CHR) != r.chr {
+			t.Errorf("Trainer %v, %d bytes of PRG, %d bytes of CHR", c.Trainer != nil, len(c.PRG), len(c.CHR))
+		}
+
+		if c.PRG[0] != 0x88 || c.PRG[len(c.PRG)-1] != 0x88 || c.HasCHRRAM() != (r.chr == 0) {
+			t.Error("PRG ROM was not read from after the header and trainer")
+		}
+	}
+}
+
+func TestReadDiskDude(t *testing.T) {
+	file := rom(1, 1, 0x10, 0x00, false)
+	copy(file[7:], "DiskDude!")
+
+	c, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Mapper != 1 {
+		t.Errorf("Mapper %d != 1", c.Mapper)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, r := range []struct {
+		file  []uint8
+		error string
+	}{
+		{[]uint8("NES\x1a\x01"), "reading iNES header"},
+		{[]uint8("NEZ\x1a\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "not an iNES file"},
+		{rom(0, 1, 0x00, 0x00, false), "no PRG ROM"},
+		{rom(1, 1, 0x00, 0x00, false)[:headerSize+100], "PRG ROM is 16384 bytes but only 100 were read"},
+		{rom(1, 1, 0x00, 0x00, false)[:headerSize+prgBankSize], "CHR ROM is 8192 bytes but only 0 were read"},
+		{rom(1, 1, 0x04, 0x00, true)[:headerSize+10], "trainer is 512 bytes"},
+	} {
+		_, err := Read(bytes.NewReader(r.file))
+		if err == nil || !strings.Contains(err.Error(), r.error) {
+			t.Errorf("Error %v does not contain %q", err, r.error)
+		}
+	}
+}
+
+func TestNROM(t *testing.T) {
+	file := rom(1, 1, 0x00, 0x00, false)
+	prg := file[headerSize:]
+	prg[0x0000] = 0xa9 // LDA #$42
+	prg[0x0001] = 0x42
+	prg[0x3ffc] = 0x00
+	prg[0x3ffd] = 0xc0
+
+	cart, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrom, err := NewNROM(cart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := cpu.NewBus()
+	bus.Map(cpu.Mapping{Name: "RAM", Start: 0x0000, End: 0x1fff, Mask: 0x07ff, Device: cpu.NewBasicMemory(0x0800)})
+	bus.Map(cpu.Mapping{Name: "PRG", Start: 0x8000, End: 0xffff, Device: nrom})
+
+	c := cpu.NewCPU(bus, cpu.WithVariant(cpu.Ricoh2A03))
+	c.Reset()
+	if c.Registers.PC != 0xc000 {
+		t.Fatalf("PC %#04x != 0xc000", c.Registers.PC)
+	}
+	if _, err = c.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Registers.A != 0x42 {
+		t.Errorf("Register A 0x42 != %#02x", c.Registers.A)
+	}
+
+	bus.Write(0x8000, 0x00)
+	if bus.Read(0x8000) != 0xa9 {
+		t.Error("Write changed the ROM")
+	}
+	bus.Poke(0x8000, 0xea)
+	if bus.Peek(0xc000) != 0xea {
+		t.Error("Poke did not change the mirrored ROM")
+	}
+
+	cart.Mapper = 4
+	if _, err = NewNROM(cart); err == nil {
+		t.Error("NewNROM accepted mapper 4")
+	}
+}
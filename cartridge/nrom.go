@@ -0,0 +1,46 @@
+package cartridge
+
+import "fmt"
+
+// NROM is the PRG ROM of a mapper 0 cartridge as a device to map into a
+// cpu.Bus at 0x8000-0xffff, where 16 KiB of PRG ROM is mirrored at 0xc000.
+// Writes are ignored
+type NROM struct {
+	prg  []uint8
+	mask uint16
+}
+
+// NewNROM returns the PRG ROM of c, which must use mapper 0
+func NewNROM(c *Cartridge) (*NROM, error) {
+	if c.Mapper != 0 {
+		return nil, fmt.Errorf("mapper %d is not NROM", c.Mapper)
+	}
+	if len(c.PRG) != prgBankSize && len(c.PRG) != 2*prgBankSize {
+		return nil, fmt.Errorf("NROM can not have %d KiB of PRG ROM", len(c.PRG)/1024)
+	}
+	return &NROM{prg: c.PRG, mask: uint16(len(c.PRG) - 1)}, nil
+}
+
+// Reset does nothing, ROM keeps its contents
+func (n *NROM) Reset() {}
+
+func (n *NROM) Read(address uint16) uint8 {
+	return n.prg[address&n.mask]
+}
+
+// Write drops value as ROM can't be written, the ROM byte is returned as the
+// old value since it's still there
+func (n *NROM) Write(address uint16, value uint8) (oldValue uint8) {
+	return n.prg[address&n.mask]
+}
+
+func (n *NROM) Peek(address uint16) uint8 {
+	return n.prg[address&n.mask]
+}
+
+// Poke changes the ROM, for patching and debugging
+func (n *NROM) Poke(address uint16, value uint8) (oldValue uint8) {
+	oldValue = n.prg[address&n.mask]
+	n.prg[address&n.mask] = value
+	return
+}
@@ -74,33 +74,151 @@ const (
 
 // CPU represents a 6502 CPU
 type CPU struct {
-	Registers    Registers
-	Memory       Memory
-	Instructions InstructionTable
-	decimalMode  bool
-	breakError   bool
-	Irq          bool
-	Nmi          bool
-	Rst          bool
+	Registers      Registers
+	Memory         Memory
+	Instructions   InstructionTable
+	variant        Variant
+	decimalMode    bool
+	breakError     bool
+	illegalOpcodes bool
+	jammed         bool
+	jam            OpCode
+	cycleAccurate  bool
+	tick           func()
+	cycles         uint64
+	nmiLine        bool
+	nmiPending     bool
+	nmiPolled      bool
+	irqPending     bool
+	irqPolled      bool
+	Irq            IrqLine // IRQ line, level triggered, masked by I
+	Nmi            bool    // NMI line, edge triggered
+	Rst            bool    // reset request, cleared once serviced
 }
 
-func NewCPU(mem Memory) *CPU {
-	instructions := NewInstructionTable()
-	instructions.InitInstructions()
+// Variant is the 6502 chip emulated by a CPU
+type Variant uint8
 
-	return &CPU{
-		Registers:    NewRegisters(),
-		Memory:       mem,
-		Instructions: instructions,
-		decimalMode:  true,
-		breakError:   false,
+const (
+	// NMOS6502 the original MOS 6502, with decimal mode, the JMP indirect
+	// page wrap bug and the unofficial and JAM opcodes
+	NMOS6502 Variant = iota
+	// Ricoh2A03 the NES CPU, an NMOS 6502 without decimal mode
+	Ricoh2A03
+	// CMOS65C02 the 65C02, which adds BRA, STZ, TRB, TSB, PHX, PLX, PHY,
+	// PLY and (zp) addressing, fixes JMP indirect and the decimal flags,
+	// and executes undefined opcodes as NOPs
+	CMOS65C02
+)
+
+func (v Variant) String() string {
+	switch v {
+	case NMOS6502:
+		return "NMOS 6502"
+	case Ricoh2A03:
+		return "Ricoh 2A03"
+	case CMOS65C02:
+		return "CMOS 65C02"
+	}
+	return fmt.Sprintf("Variant(%d)", uint8(v))
+}
+
+// Option configures a CPU created by NewCPU
+type Option func(*CPU)
+
+// WithVariant selects the chip the CPU emulates, NMOS6502 by default. It
+// picks the InstructionTable and whether decimal mode is available
+func WithVariant(variant Variant) Option {
+	return func(cpu *CPU) {
+		cpu.variant = variant
+		cpu.decimalMode = variant != Ricoh2A03
+	}
+}
+
+// WithIllegalOpcodes adds the unofficial NMOS opcodes to the CPU's
+// InstructionTable. Without it they return BadOpCodeError. It has no effect
+// on the 65C02, which has no unofficial opcodes
+func WithIllegalOpcodes() Option {
+	return func(cpu *CPU) {
+		cpu.illegalOpcodes = true
+	}
+}
+
+// WithCycleAccuracy makes every CPU cycle perform exactly one Memory.Read
+// or Memory.Write, including the dummy reads and writes of the real 6502,
+// and counts cycles from that bus activity instead of the InstructionTable.
+// tick, if not nil, is called at the start of every cycle so that other
+// devices can be clocked in between the CPU's bus accesses
+func WithCycleAccuracy(tick func()) Option {
+	return func(cpu *CPU) {
+		cpu.cycleAccurate = true
+		cpu.tick = tick
+	}
+}
+
+func NewCPU(mem Memory, options ...Option) *CPU {
+	cpu := &CPU{
+		Registers:   NewRegisters(),
+		Memory:      mem,
+		decimalMode: true,
+		breakError:  false,
+	}
+
+	for _, option := range options {
+		option(cpu)
 	}
+
+	switch cpu.variant {
+	case CMOS65C02:
+		cpu.Instructions = NewCMOSInstructionTable()
+		cpu.Instructions.InitInstructions()
+		cpu.Instructions.InitCMOSInstructions()
+	default:
+		cpu.Instructions = NewInstructionTable()
+		cpu.Instructions.InitInstructions()
+		if cpu.illegalOpcodes {
+			cpu.Instructions.InitIllegalInstructions()
+		}
+	}
+
+	return cpu
+}
+
+// Variant returns the chip the CPU emulates
+func (cpu *CPU) Variant() Variant {
+	return cpu.variant
 }
 
+// Reset is the same as PowerOn
 func (cpu *CPU) Reset() {
-	cpu.Registers.Reset()
+	cpu.PowerOn()
+}
+
+// PowerOn resets Memory to its power on contents and clears A, X, Y, SP and
+// all flags but I before running the reset sequence, which leaves SP at 0xfd
+// and loads PC from the reset vector
+func (cpu *CPU) PowerOn() {
+	cpu.Registers = Registers{P: I | U}
 	cpu.Memory.Reset()
+
+	cpu.cycles = 0
+	cpu.nmiLine = false
+	cpu.nmiPending = false
+	cpu.nmiPolled = false
+	cpu.irqPending = false
+	cpu.irqPolled = false
+
+	cpu.SoftReset()
+}
+
+// SoftReset runs the reset sequence as when the reset button is pressed. RAM
+// and registers are left intact, except that SP is decremented by 3 without
+// writing to the stack, I is set and PC is loaded from the reset vector
+func (cpu *CPU) SoftReset() {
 	cpu.ExecuteRst()
+	if !cpu.cycleAccurate {
+		cpu.cycles += 7
+	}
 }
 
 func (cpu *CPU) IndexToRegister(which Index) uint8 {
@@ -117,7 +235,15 @@ func (cpu *CPU) IndexToRegister(which Index) uint8 {
 type BadOpCodeError OpCode
 
 func (b BadOpCodeError) Error() string {
-	return fmt.Sprintf("No such opcode %#02x", b)
+	return fmt.Sprintf("No such opcode %#02x", uint8(b))
+}
+
+// CPUJammedError is returned by Execute after a JAM opcode has halted the
+// CPU. Only Reset or a Rst interrupt recovers from it
+type CPUJammedError OpCode
+
+func (j CPUJammedError) Error() string {
+	return fmt.Sprintf("CPU jammed by opcode %#02x", uint8(j))
 }
 
 type BrkOpCodeError OpCode
@@ -126,14 +252,84 @@ func (b BrkOpCodeError) Error() string {
 	return fmt.Sprintf("Executed BRK opcode")
 }
 
+// Cycles returns the total number of cycles executed by the CPU
+func (cpu *CPU) Cycles() uint64 {
+	return cpu.cycles
+}
+
+// State is everything about a CPU that changes as it runs, apart from its
+// memory. It is saved with SaveState and put back with RestoreState
+type State struct {
+	Registers  Registers
+	Irq        IrqLine
+	Nmi        bool
+	Rst        bool
+	cycles     uint64
+	jammed     bool
+	jam        OpCode
+	nmiLine    bool
+	nmiPending bool
+	nmiPolled  bool
+	irqPending bool
+	irqPolled  bool
+}
+
+// SaveState returns the state of the CPU
+func (cpu *CPU) SaveState() State {
+	return State{
+		Registers:  cpu.Registers,
+		Irq:        cpu.Irq,
+		Nmi:        cpu.Nmi,
+		Rst:        cpu.Rst,
+		cycles:     cpu.cycles,
+		jammed:     cpu.jammed,
+		jam:        cpu.jam,
+		nmiLine:    cpu.nmiLine,
+		nmiPending: cpu.nmiPending,
+		nmiPolled:  cpu.nmiPolled,
+		irqPending: cpu.irqPending,
+		irqPolled:  cpu.irqPolled,
+	}
+}
+
+// RestoreState returns the CPU to a state saved with SaveState
+func (cpu *CPU) RestoreState(s State) {
+	cpu.Registers = s.Registers
+	cpu.Irq = s.Irq
+	cpu.Nmi = s.Nmi
+	cpu.Rst = s.Rst
+	cpu.cycles = s.cycles
+	cpu.jammed = s.jammed
+	cpu.jam = s.jam
+	cpu.nmiLine = s.nmiLine
+	cpu.nmiPending = s.nmiPending
+	cpu.nmiPolled = s.nmiPolled
+	cpu.irqPending = s.irqPending
+	cpu.irqPolled = s.irqPolled
+}
+
 // Execute takes instruction of PC and executes it in the number
-// of cycles as returned by the instruction's Exec function.
+// of cycles as returned by the instruction's Exec function, or in
+// cycle-accurate mode in the number of bus accesses it made.
 // Returns the number of cycles executed and any error, if any.
 func (cpu *CPU) Execute() (cycles uint16, err error) {
+	if cpu.jammed && !cpu.Rst {
+		return 0, CPUJammedError(cpu.jam)
+	}
+
+	start := cpu.cycles
+	defer func() {
+		if cpu.cycleAccurate {
+			cycles = uint16(cpu.cycles - start)
+		} else {
+			cpu.cycles += uint64(cycles)
+		}
+	}()
+
 	cycles += cpu.ExecuteInterrupt()
 
 	// fetch
-	opcode := OpCode(cpu.Memory.Read(cpu.Registers.PC))
+	opcode := OpCode(cpu.fetch(cpu.Registers.PC))
 	inst := cpu.Instructions.opcodes[opcode]
 	if inst == nil {
 		return 0, BadOpCodeError(opcode)
@@ -143,6 +339,10 @@ func (cpu *CPU) Execute() (cycles uint16, err error) {
 	cpu.Registers.PC++
 	cycles += cpu.Instructions.Execute(cpu, opcode)
 
+	if cpu.jammed {
+		return cycles, CPUJammedError(cpu.jam)
+	}
+
 	if cpu.breakError && opcode == 0x00 {
 		return cycles, BrkOpCodeError(opcode)
 	}
@@ -162,47 +362,114 @@ func (cpu *CPU) Run() (err error) {
 // Interrupts
 // ==========
 
+// poll samples the interrupt lines. The 6502 does this at the end of every
+// cycle and decides whether to service an interrupt after an instruction
+// from what it sampled at the end of the instruction's second-to-last cycle,
+// which is why CLI, SEI and PLP only take effect after the next instruction.
+// Outside cycle-accurate mode the lines are sampled once per instruction,
+// and RTI samples IRQ again as it changes I early enough to not be delayed
+func (cpu *CPU) poll() {
+	cpu.nmiPolled = cpu.nmiPending
+	if cpu.Nmi && !cpu.nmiLine {
+		cpu.nmiPending = true
+	}
+	cpu.nmiLine = cpu.Nmi
+
+	cpu.irqPolled = cpu.irqPending
+	cpu.irqPending = cpu.Irq.Asserted() && cpu.Registers.P&I == 0
+}
+
+// ExecuteInterrupt services a reset, NMI or IRQ if one was detected during
+// the previous instruction, returning the cycles it took
 func (cpu *CPU) ExecuteInterrupt() (cycles uint16) {
+	if !cpu.cycleAccurate {
+		cpu.poll()
+	}
+
 	cycles = 7
 	switch {
-	case cpu.Irq && cpu.Registers.P&I == 0:
-		cpu.ExecuteIrq()
-		cpu.Irq = false
-	case cpu.Nmi:
-		cpu.ExecuteNmi()
-		cpu.Nmi = false
 	case cpu.Rst:
 		cpu.ExecuteRst()
 		cpu.Rst = false
+	case cpu.nmiPolled || cpu.irqPolled:
+		cpu.interrupt(false)
 	default:
 		cycles = 0
 	}
 	return
 }
 
+// ExecuteIrq runs the interrupt sequence through the IRQ vector, unless a
+// pending NMI hijacks it
 func (cpu *CPU) ExecuteIrq() {
-	cpu.push16(cpu.Registers.PC)
-	cpu.push8(uint8((cpu.Registers.P | U) & ^B))
-	cpu.Registers.P |= I
-
-	low := cpu.Memory.Read(0xfffe)
-	high := cpu.Memory.Read(0xffff)
-	cpu.Registers.PC = (uint16(high)<<8 | uint16(low))
+	cpu.interrupt(false)
 }
 
+// ExecuteNmi runs the interrupt sequence through the NMI vector
 func (cpu *CPU) ExecuteNmi() {
+	cpu.nmiPending = true
+	cpu.interrupt(false)
+}
+
+// interrupt runs the sequence shared by BRK, IRQ and NMI. Which vector is
+// used is only decided after PC has been pushed, so an NMI detected until
+// then hijacks a BRK or IRQ. P is still pushed with B set for BRK and clear
+// otherwise. The 65C02 also clears D
+func (cpu *CPU) interrupt(brk bool) {
+	cpu.dummyRead(cpu.Registers.PC)
+	if brk {
+		cpu.Registers.PC++
+	} else {
+		cpu.dummyRead(cpu.Registers.PC)
+	}
 	cpu.push16(cpu.Registers.PC)
-	cpu.push8(uint8((cpu.Registers.P | U) & ^B))
+
+	vector := uint16(0xfffe)
+	if cpu.nmiPending {
+		cpu.nmiPending = false
+		vector = 0xfffa
+	}
+
+	if brk {
+		cpu.push8(uint8(cpu.Registers.P | B | U))
+	} else {
+		cpu.push8(uint8((cpu.Registers.P | U) & ^B))
+	}
 	cpu.Registers.P |= I
+	if cpu.variant == CMOS65C02 {
+		cpu.Registers.P &= ^D
+	}
 
-	low := cpu.Memory.Read(0xfffa)
-	high := cpu.Memory.Read(0xfffb)
+	low := cpu.read(vector)
+	high := cpu.read(vector + 1)
 	cpu.Registers.PC = (uint16(high) << 8) | uint16(low)
+
+	// the first instruction of the handler always runs before another
+	// interrupt is serviced
+	cpu.nmiPolled = false
+	cpu.irqPolled = false
+	if !cpu.cycleAccurate {
+		cpu.irqPending = false
+	}
+}
+
+// Jammed returns true if a JAM opcode has halted the CPU
+func (cpu *CPU) Jammed() bool {
+	return cpu.jammed
 }
 
 func (cpu *CPU) ExecuteRst() {
-	low := cpu.Memory.Read(0xfffc)
-	high := cpu.Memory.Read(0xfffd)
+	cpu.jammed = false
+	cpu.dummyRead(cpu.Registers.PC)
+	cpu.dummyRead(cpu.Registers.PC)
+	// the stack is accessed as for an interrupt, but reads replace writes
+	for i := 0; i < 3; i++ {
+		cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+		cpu.Registers.SP--
+	}
+	cpu.Registers.P |= I
+	low := cpu.read(0xfffc)
+	high := cpu.read(0xfffd)
 	cpu.Registers.PC = (uint16(high) << 8) | uint16(low)
 }
 
@@ -249,7 +516,7 @@ func (cpu *CPU) rmwAddress(opcode OpCode, status *InstructionStatus) (address ui
 		case 0x01:
 			address = cpu.zeroPageAddress()
 		case 0x02:
-			address = 0 // UNUSED
+			address = cpu.accumulatorAddress(status)
 		case 0x03:
 			address = cpu.absoluteAddress()
 		}
@@ -306,16 +573,43 @@ func (cpu *CPU) controlAddress(opcode OpCode, status *InstructionStatus) (addres
 	return
 }
 
+// the 65C02 adds zero page indirect to the alu opcodes in the slot of the
+// (zp),Y row that ends with 10
+func (cpu *CPU) cmosAluAddress(opcode OpCode, status *InstructionStatus) (address uint16) {
+	if opcode&0x1f == 0x12 {
+		address = cpu.zeroPageIndirectAddress()
+	} else {
+		address = cpu.aluAddress(opcode, status)
+	}
+	return
+}
+
+// unofficial opcodes end with 11 and mostly decode like alu opcodes, except
+// that they index with Y instead of X where the rmw opcodes do
+func (cpu *CPU) illegalAddress(opcode OpCode, status *InstructionStatus) (address uint16) {
+	switch {
+	case opcode == 0x9c:
+		// SHY is the one in the 0x9x row that indexes by X
+		address = cpu.indexedAbsoluteAddress(X, status)
+	case opcode&0x10 != 0 && opcode&0x04 != 0:
+		address = cpu.rmwAddress(opcode, status)
+	default:
+		address = cpu.aluAddress(opcode, status)
+	}
+	return
+}
+
 // E.1
 func (cpu *CPU) zeroPageAddress() (result uint16) {
-	result = uint16(cpu.Memory.Read(cpu.Registers.PC))
+	result = uint16(cpu.read(cpu.Registers.PC))
 	cpu.Registers.PC++
 	return
 }
 
 // E.2
 func (cpu *CPU) indexedZeroPageAddress(index Index) (result uint16) {
-	value := cpu.Memory.Read(cpu.Registers.PC)
+	value := cpu.read(cpu.Registers.PC)
+	cpu.dummyRead(uint16(value))
 	result = uint16(value + cpu.IndexToRegister(index))
 	cpu.Registers.PC++
 	return
@@ -323,8 +617,8 @@ func (cpu *CPU) indexedZeroPageAddress(index Index) (result uint16) {
 
 // E.3
 func (cpu *CPU) absoluteAddress() (result uint16) {
-	low := cpu.Memory.Read(cpu.Registers.PC)
-	high := cpu.Memory.Read(cpu.Registers.PC + 1)
+	low := cpu.read(cpu.Registers.PC)
+	high := cpu.read(cpu.Registers.PC + 1)
 	result = (uint16(high) << 8) | uint16(low)
 	cpu.Registers.PC += 2
 	return
@@ -333,8 +627,8 @@ func (cpu *CPU) absoluteAddress() (result uint16) {
 // E.4
 func (cpu *CPU) indexedAbsoluteAddress(index Index, status *InstructionStatus) (result uint16) {
 
-	low := cpu.Memory.Read(cpu.Registers.PC)
-	high := cpu.Memory.Read(cpu.Registers.PC + 1)
+	low := cpu.read(cpu.Registers.PC)
+	high := cpu.read(cpu.Registers.PC + 1)
 
 	address := (uint16(high) << 8) | uint16(low)
 	result = address + uint16(cpu.IndexToRegister(index))
@@ -344,27 +638,48 @@ func (cpu *CPU) indexedAbsoluteAddress(index Index, status *InstructionStatus) (
 	if status != nil && !SamePage(address, result) {
 		*status |= PageCross
 	}
+	cpu.indexedDummyRead(address, result, status)
 
 	return
 }
 
 // E.5
 func (cpu *CPU) indirectAddress() (result uint16) {
-	low := cpu.Memory.Read(cpu.Registers.PC)
-	high := cpu.Memory.Read(cpu.Registers.PC + 1)
+	low := cpu.read(cpu.Registers.PC)
+	high := cpu.read(cpu.Registers.PC + 1)
 	cpu.Registers.PC += 2
-	// 6502 had a bug where it incremented only the high byte instead
-	// of the whole 16bit address when computing the address.
-	high = cpu.Memory.Read((uint16(high) << 8) | uint16(low+1))
-	low = cpu.Memory.Read((uint16(high) << 8) | uint16(low))
+	pointer := (uint16(high) << 8) | uint16(low)
+	if cpu.variant == CMOS65C02 {
+		// the 65C02 fixed the bug below at the cost of an extra cycle
+		cpu.dummyRead(cpu.Registers.PC - 1)
+		low = cpu.read(pointer)
+		high = cpu.read(pointer + 1)
+	} else {
+		// 6502 had a bug where it incremented only the low byte instead
+		// of the whole 16bit address when computing the address.
+		low = cpu.read(pointer)
+		high = cpu.read((pointer & 0xff00) | uint16(uint8(pointer)+1))
+	}
 	result = (uint16(high) << 8) | uint16(low)
 	return
 }
 
-// E.6 Implied (CLD, NOOP)
+// E.6 Implied (CLD, NOOP). There is no operand, but the byte following the
+// opcode is read and discarded
+func (cpu *CPU) impliedAddress() {
+	cpu.dummyRead(cpu.Registers.PC)
+}
 
 // E.7 Accumulator Arithmetic shift left, logical shift right, rotate left,
-// rotate right
+// rotate right. There is no operand address, the Accumulator status tells
+// the instruction to operate on A instead of memory
+func (cpu *CPU) accumulatorAddress(status *InstructionStatus) (result uint16) {
+	if status != nil {
+		*status |= Accumulator
+	}
+	cpu.dummyRead(cpu.Registers.PC)
+	return
+}
 
 // E.8
 func (cpu *CPU) immediateAddress() (result uint16) {
@@ -375,7 +690,7 @@ func (cpu *CPU) immediateAddress() (result uint16) {
 
 // E.9
 func (cpu *CPU) relativeAddress() (result uint16) {
-	value := uint16(cpu.Memory.Read(cpu.Registers.PC))
+	value := uint16(cpu.read(cpu.Registers.PC))
 	cpu.Registers.PC++
 
 	var offset uint16
@@ -390,10 +705,11 @@ func (cpu *CPU) relativeAddress() (result uint16) {
 
 // E.10 aka pre-indexed
 func (cpu *CPU) indexedIndirectAddress() (result uint16) {
-	value := cpu.Memory.Read(cpu.Registers.PC)
+	value := cpu.read(cpu.Registers.PC)
+	cpu.dummyRead(uint16(value))
 	address := uint16(value + cpu.Registers.X)
-	low := cpu.Memory.Read(address)
-	high := cpu.Memory.Read((address + 1) & 0x00ff)
+	low := cpu.read(address)
+	high := cpu.read((address + 1) & 0x00ff)
 	result = (uint16(high) << 8) | uint16(low)
 	cpu.Registers.PC++
 	return
@@ -401,11 +717,11 @@ func (cpu *CPU) indexedIndirectAddress() (result uint16) {
 
 // E.11 aka post-indexed
 func (cpu *CPU) indirectIndexedAddress(status *InstructionStatus) (result uint16) {
-	value := cpu.Memory.Read(cpu.Registers.PC)
+	value := cpu.read(cpu.Registers.PC)
 	address := uint16(value)
 	cpu.Registers.PC++
-	low := cpu.Memory.Read(address)
-	high := cpu.Memory.Read((address + 1) & 0x00ff)
+	low := cpu.read(address)
+	high := cpu.read((address + 1) & 0x00ff)
 
 	address = (uint16(high) << 8) | uint16(low)
 	result = address + uint16(cpu.Registers.Y)
@@ -413,14 +729,106 @@ func (cpu *CPU) indirectIndexedAddress(status *InstructionStatus) (result uint16
 	if status != nil && !SamePage(address, result) {
 		*status |= PageCross
 	}
+	cpu.indexedDummyRead(address, result, status)
 	return
 }
 
+// E.12 65C02 zero page indirect
+func (cpu *CPU) zeroPageIndirectAddress() (result uint16) {
+	address := uint16(cpu.read(cpu.Registers.PC))
+	cpu.Registers.PC++
+	low := cpu.read(address)
+	high := cpu.read((address + 1) & 0x00ff)
+	result = (uint16(high) << 8) | uint16(low)
+	return
+}
+
+// E.13 65C02 absolute indexed indirect, only used by JMP
+func (cpu *CPU) indexedAbsoluteIndirectAddress() (result uint16) {
+	low := cpu.read(cpu.Registers.PC)
+	high := cpu.read(cpu.Registers.PC + 1)
+	cpu.Registers.PC += 2
+	cpu.dummyRead(cpu.Registers.PC - 1)
+	pointer := ((uint16(high) << 8) | uint16(low)) + uint16(cpu.Registers.X)
+	low = cpu.read(pointer)
+	high = cpu.read(pointer + 1)
+	result = (uint16(high) << 8) | uint16(low)
+	return
+}
+
+// indexedDummyRead performs the read the 6502 makes before it has carried
+// the index into the high byte of the address. Reads only make it when the
+// index crosses a page, stores and read-modify-write instructions always do.
+// The 65C02 reads the last operand byte again instead
+func (cpu *CPU) indexedDummyRead(address uint16, result uint16, status *InstructionStatus) {
+	if status != nil && (*status&Store != 0 || !SamePage(address, result)) {
+		if cpu.variant == CMOS65C02 {
+			cpu.dummyRead(cpu.Registers.PC - 1)
+		} else {
+			cpu.dummyRead((address & 0xff00) | (result & 0x00ff))
+		}
+	}
+}
+
 // Helpers
 // =======
 
+// cycle starts a new CPU cycle in cycle-accurate mode
+func (cpu *CPU) cycle() {
+	if cpu.cycleAccurate {
+		cpu.cycles++
+		if cpu.tick != nil {
+			cpu.tick()
+		}
+	}
+}
+
+func (cpu *CPU) read(address uint16) (value uint8) {
+	cpu.cycle()
+	value = cpu.Memory.Read(address)
+	if cpu.cycleAccurate {
+		cpu.poll()
+	}
+	return
+}
+
+// fetch is read for opcodes, which memory can tell apart from other reads
+// by implementing FetchMemory
+func (cpu *CPU) fetch(address uint16) (value uint8) {
+	cpu.cycle()
+	value = Fetch(cpu.Memory, address)
+	if cpu.cycleAccurate {
+		cpu.poll()
+	}
+	return
+}
+
+func (cpu *CPU) write(address uint16, value uint8) {
+	cpu.cycle()
+	cpu.Memory.Write(address, value)
+	if cpu.cycleAccurate {
+		cpu.poll()
+	}
+}
+
+// dummyRead performs a read whose value the 6502 discards. It is only made
+// in cycle-accurate mode
+func (cpu *CPU) dummyRead(address uint16) {
+	if cpu.cycleAccurate {
+		cpu.read(address)
+	}
+}
+
+// dummyWrite performs a write that the 6502 immediately overwrites. It is
+// only made in cycle-accurate mode
+func (cpu *CPU) dummyWrite(address uint16, value uint8) {
+	if cpu.cycleAccurate {
+		cpu.write(address, value)
+	}
+}
+
 func (cpu *CPU) push8(value uint8) {
-	cpu.Memory.Write(0x0100|uint16(cpu.Registers.SP), value)
+	cpu.write(0x0100|uint16(cpu.Registers.SP), value)
 	cpu.Registers.SP--
 }
 
@@ -429,6 +837,48 @@ func (cpu *CPU) push16(value uint16) {
 	cpu.push8(uint8(value))
 }
 
+func (cpu *CPU) pull8() (value uint8) {
+	cpu.Registers.SP++
+	value = cpu.read(0x0100 | uint16(cpu.Registers.SP))
+	return
+}
+
+func (cpu *CPU) pull16() (value uint16) {
+	low := cpu.pull8()
+	high := cpu.pull8()
+	value = (uint16(high) << 8) | uint16(low)
+	return
+}
+
+// modify reads memory address, applies op to the value and writes the
+// result back. Like the 6502 it writes the unmodified value first, the 65C02
+// reads it again instead
+func (cpu *CPU) modify(address uint16, op func(uint8) uint8) {
+	value := cpu.read(address)
+	if cpu.variant == CMOS65C02 {
+		cpu.dummyRead(address)
+	} else {
+		cpu.dummyWrite(address, value)
+	}
+	cpu.write(address, op(value))
+}
+
+func (cpu *CPU) setCFlag(set bool) {
+	if set {
+		cpu.Registers.P |= C
+	} else {
+		cpu.Registers.P &= ^C
+	}
+}
+
+func (cpu *CPU) setVFlag(set bool) {
+	if set {
+		cpu.Registers.P |= V
+	} else {
+		cpu.Registers.P &= ^V
+	}
+}
+
 func (cpu *CPU) setZFlag(value uint8) uint8 {
 	if value == 0 {
 		cpu.Registers.P |= Z
@@ -454,30 +904,730 @@ func (cpu *CPU) setZNFlags(value uint8) uint8 {
 
 // Lda loads A with memory address, setting Z and N, if required
 func (cpu *CPU) Lda(address uint16) {
-	cpu.Registers.A = cpu.setZNFlags(cpu.Memory.Read(address))
+	cpu.Registers.A = cpu.setZNFlags(cpu.read(address))
 }
 
 // Ldx loads X with memory address, setting Z and N, if required
 func (cpu *CPU) Ldx(address uint16) {
-	cpu.Registers.X = cpu.setZNFlags(cpu.Memory.Read(address))
+	cpu.Registers.X = cpu.setZNFlags(cpu.read(address))
 }
 
 // Ldy loads Y with memory address, setting Z and N, if required
 func (cpu *CPU) Ldy(address uint16) {
-	cpu.Registers.Y = cpu.setZNFlags(cpu.Memory.Read(address))
+	cpu.Registers.Y = cpu.setZNFlags(cpu.read(address))
 }
 
 // Sta stores A in memory address
 func (cpu *CPU) Sta(address uint16) {
-	cpu.Memory.Write(address, cpu.Registers.A)
+	cpu.write(address, cpu.Registers.A)
 }
 
 // Stx stores X in memory address
 func (cpu *CPU) Stx(address uint16) {
-	cpu.Memory.Write(address, cpu.Registers.X)
+	cpu.write(address, cpu.Registers.X)
 }
 
 // Sty stores Y in memory address
 func (cpu *CPU) Sty(address uint16) {
-	cpu.Memory.Write(address, cpu.Registers.Y)
+	cpu.write(address, cpu.Registers.Y)
+}
+
+// Adc adds memory address and C to A, setting C, Z, V and N, if required.
+// In decimal mode Z is computed from the binary sum and N and V from the
+// result before the high nibble is adjusted, as on the NMOS 6502. The 65C02
+// computes N and Z from the decimal result
+func (cpu *CPU) Adc(address uint16) {
+	cpu.adc(cpu.read(address))
+}
+
+func (cpu *CPU) adc(value uint8) {
+	a := uint16(cpu.Registers.A)
+	b := uint16(value)
+	carry := uint16(cpu.Registers.P & C)
+
+	if !cpu.decimalMode || cpu.Registers.P&D == 0 {
+		result := a + b + carry
+		cpu.setCFlag(result > 0xff)
+		cpu.setVFlag((a^result)&(b^result)&0x80 != 0)
+		cpu.Registers.A = cpu.setZNFlags(uint8(result))
+		return
+	}
+
+	result := a&0x0f + b&0x0f + carry
+	if result > 0x09 {
+		result += 0x06
+	}
+	if result > 0x0f {
+		result = result&0x0f + a&0xf0 + b&0xf0 + 0x10
+	} else {
+		result = result&0x0f + a&0xf0 + b&0xf0
+	}
+
+	cpu.setZFlag(uint8(a + b + carry))
+	cpu.setNFlag(uint8(result))
+	cpu.setVFlag((a^result)&0x80 != 0 && (a^b)&0x80 == 0)
+
+	if result&0x1f0 > 0x90 {
+		result += 0x60
+	}
+	cpu.setCFlag(result&0xff0 > 0xf0)
+	cpu.Registers.A = uint8(result)
+	if cpu.variant == CMOS65C02 {
+		cpu.setZNFlags(cpu.Registers.A)
+	}
+}
+
+// Sbc subtracts memory address and the complement of C from A, setting C,
+// Z, V and N, if required. In decimal mode all flags are computed from the
+// binary difference, as on the NMOS 6502. The 65C02 adjusts the result
+// differently and computes N and Z from it
+func (cpu *CPU) Sbc(address uint16) {
+	cpu.sbc(cpu.read(address))
+}
+
+func (cpu *CPU) sbc(value uint8) {
+	if !cpu.decimalMode || cpu.Registers.P&D == 0 {
+		cpu.adc(^value)
+		return
+	}
+
+	a := uint16(cpu.Registers.A)
+	b := uint16(value)
+	borrow := uint16(^cpu.Registers.P & C)
+	binary := a - b - borrow
+
+	if cpu.variant == CMOS65C02 {
+		result := binary
+		if binary&0x100 != 0 {
+			result -= 0x60
+		}
+		if (a&0x0f-b&0x0f-borrow)&0x100 != 0 {
+			result -= 0x06
+		}
+		cpu.setCFlag(binary < 0x100)
+		cpu.setVFlag((a^binary)&0x80 != 0 && (a^b)&0x80 != 0)
+		cpu.Registers.A = cpu.setZNFlags(uint8(result))
+		return
+	}
+
+	result := a&0x0f - b&0x0f - borrow
+	if result&0x10 != 0 {
+		result = (result-0x06)&0x0f | (a&0xf0 - b&0xf0 - 0x10)
+	} else {
+		result = result&0x0f | (a&0xf0 - b&0xf0)
+	}
+	if result&0x100 != 0 {
+		result -= 0x60
+	}
+
+	cpu.setCFlag(binary < 0x100)
+	cpu.setZNFlags(uint8(binary))
+	cpu.setVFlag((a^binary)&0x80 != 0 && (a^b)&0x80 != 0)
+	cpu.Registers.A = uint8(result)
+}
+
+// And performs a bitwise and of A with memory address, storing the result
+// in A and setting Z and N, if required
+func (cpu *CPU) And(address uint16) {
+	cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A & cpu.read(address))
+}
+
+// Ora performs a bitwise or of A with memory address, storing the result
+// in A and setting Z and N, if required
+func (cpu *CPU) Ora(address uint16) {
+	cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A | cpu.read(address))
+}
+
+// Eor performs a bitwise exclusive or of A with memory address, storing the
+// result in A and setting Z and N, if required
+func (cpu *CPU) Eor(address uint16) {
+	cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A ^ cpu.read(address))
+}
+
+// Cmp compares A with memory address, setting C, Z and N, if required
+func (cpu *CPU) Cmp(address uint16) {
+	cpu.compare(cpu.Registers.A, cpu.read(address))
+}
+
+func (cpu *CPU) compare(register uint8, value uint8) {
+	cpu.setCFlag(register >= value)
+	cpu.setZNFlags(register - value)
+}
+
+// Asl shifts memory address left one bit, setting C, Z and N, if required
+func (cpu *CPU) Asl(address uint16) {
+	cpu.modify(address, cpu.asl)
+}
+
+// AslA shifts A left one bit, setting C, Z and N, if required
+func (cpu *CPU) AslA() {
+	cpu.Registers.A = cpu.asl(cpu.Registers.A)
+}
+
+func (cpu *CPU) asl(value uint8) uint8 {
+	cpu.setCFlag(value&0x80 != 0)
+	return cpu.setZNFlags(value << 1)
+}
+
+// Lsr shifts memory address right one bit, setting C, Z and N, if required
+func (cpu *CPU) Lsr(address uint16) {
+	cpu.modify(address, cpu.lsr)
+}
+
+// LsrA shifts A right one bit, setting C, Z and N, if required
+func (cpu *CPU) LsrA() {
+	cpu.Registers.A = cpu.lsr(cpu.Registers.A)
+}
+
+func (cpu *CPU) lsr(value uint8) uint8 {
+	cpu.setCFlag(value&0x01 != 0)
+	return cpu.setZNFlags(value >> 1)
+}
+
+// Rol rotates memory address left one bit through C, setting C, Z and N, if
+// required
+func (cpu *CPU) Rol(address uint16) {
+	cpu.modify(address, cpu.rol)
+}
+
+// RolA rotates A left one bit through C, setting C, Z and N, if required
+func (cpu *CPU) RolA() {
+	cpu.Registers.A = cpu.rol(cpu.Registers.A)
+}
+
+func (cpu *CPU) rol(value uint8) uint8 {
+	carry := uint8(cpu.Registers.P & C)
+	cpu.setCFlag(value&0x80 != 0)
+	return cpu.setZNFlags(value<<1 | carry)
+}
+
+// Ror rotates memory address right one bit through C, setting C, Z and N, if
+// required
+func (cpu *CPU) Ror(address uint16) {
+	cpu.modify(address, cpu.ror)
+}
+
+// RorA rotates A right one bit through C, setting C, Z and N, if required
+func (cpu *CPU) RorA() {
+	cpu.Registers.A = cpu.ror(cpu.Registers.A)
+}
+
+func (cpu *CPU) ror(value uint8) uint8 {
+	carry := uint8(cpu.Registers.P & C)
+	cpu.setCFlag(value&0x01 != 0)
+	return cpu.setZNFlags(value>>1 | carry<<7)
+}
+
+// Inc increments memory address by one, setting Z and N, if required
+func (cpu *CPU) Inc(address uint16) {
+	cpu.modify(address, cpu.inc)
+}
+
+func (cpu *CPU) inc(value uint8) uint8 {
+	return cpu.setZNFlags(value + 1)
+}
+
+// Dec decrements memory address by one, setting Z and N, if required
+func (cpu *CPU) Dec(address uint16) {
+	cpu.modify(address, cpu.dec)
+}
+
+func (cpu *CPU) dec(value uint8) uint8 {
+	return cpu.setZNFlags(value - 1)
+}
+
+func (cpu *CPU) branch(address uint16, taken bool) (status InstructionStatus) {
+	if taken {
+		status |= Branched
+		// a taken branch that does not cross a page does not poll the
+		// interrupt lines on its last cycle
+		if cpu.cycleAccurate && cpu.irqPending && !cpu.irqPolled {
+			cpu.irqPending = false
+		}
+		cpu.dummyRead(cpu.Registers.PC)
+		if !SamePage(cpu.Registers.PC, address) {
+			status |= PageCross
+			cpu.dummyRead((cpu.Registers.PC & 0xff00) | (address & 0x00ff))
+		}
+		cpu.Registers.PC = address
+	}
+	return
+}
+
+// Bpl branches to address if N is clear
+func (cpu *CPU) Bpl(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&N == 0)
+}
+
+// Bmi branches to address if N is set
+func (cpu *CPU) Bmi(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&N != 0)
+}
+
+// Bvc branches to address if V is clear
+func (cpu *CPU) Bvc(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&V == 0)
+}
+
+// Bvs branches to address if V is set
+func (cpu *CPU) Bvs(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&V != 0)
+}
+
+// Bcc branches to address if C is clear
+func (cpu *CPU) Bcc(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&C == 0)
+}
+
+// Bcs branches to address if C is set
+func (cpu *CPU) Bcs(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&C != 0)
+}
+
+// Bne branches to address if Z is clear
+func (cpu *CPU) Bne(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&Z == 0)
+}
+
+// Beq branches to address if Z is set
+func (cpu *CPU) Beq(address uint16) InstructionStatus {
+	return cpu.branch(address, cpu.Registers.P&Z != 0)
+}
+
+// Jmp sets PC to address
+func (cpu *CPU) Jmp(address uint16) {
+	cpu.Registers.PC = address
+}
+
+// Jsr pushes the address of the last byte of the JSR instruction on the
+// stack and sets PC to its absolute operand. The 6502 pushes PC in between
+// reading the low and high bytes of the operand
+func (cpu *CPU) Jsr() {
+	low := cpu.read(cpu.Registers.PC)
+	cpu.Registers.PC++
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.push16(cpu.Registers.PC)
+	high := cpu.read(cpu.Registers.PC)
+	cpu.Registers.PC = (uint16(high) << 8) | uint16(low)
+}
+
+// Rts pulls PC from the stack and increments it
+func (cpu *CPU) Rts() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.PC = cpu.pull16()
+	cpu.dummyRead(cpu.Registers.PC)
+	cpu.Registers.PC++
+}
+
+// Rti pulls P and then PC from the stack. B is not a real flag and is
+// dropped from the pulled value
+func (cpu *CPU) Rti() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.P = (Status(cpu.pull8()) | U) & ^B
+	cpu.Registers.PC = cpu.pull16()
+
+	// RTI restores I before its last cycles, so unlike CLI, SEI and PLP it
+	// takes effect for the interrupt poll right after it
+	if !cpu.cycleAccurate {
+		cpu.irqPending = cpu.Irq.Asserted() && cpu.Registers.P&I == 0
+	}
+}
+
+// Bit tests A against memory address, setting Z from the result and N and V
+// from bits 7 and 6 of the memory value
+func (cpu *CPU) Bit(address uint16) {
+	value := cpu.read(address)
+	cpu.setZFlag(cpu.Registers.A & value)
+	cpu.setNFlag(value)
+	cpu.setVFlag(value&uint8(V) != 0)
+}
+
+// Cpx compares X with memory address, setting C, Z and N, if required
+func (cpu *CPU) Cpx(address uint16) {
+	cpu.compare(cpu.Registers.X, cpu.read(address))
+}
+
+// Cpy compares Y with memory address, setting C, Z and N, if required
+func (cpu *CPU) Cpy(address uint16) {
+	cpu.compare(cpu.Registers.Y, cpu.read(address))
+}
+
+// Pha pushes A on the stack
+func (cpu *CPU) Pha() {
+	cpu.push8(cpu.Registers.A)
+}
+
+// Php pushes P on the stack with B set
+func (cpu *CPU) Php() {
+	cpu.push8(uint8(cpu.Registers.P | B | U))
+}
+
+// Pla pulls A from the stack, setting Z and N, if required
+func (cpu *CPU) Pla() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.A = cpu.setZNFlags(cpu.pull8())
+}
+
+// Plp pulls P from the stack. B is not a real flag and is dropped from the
+// pulled value
+func (cpu *CPU) Plp() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.P = (Status(cpu.pull8()) | U) & ^B
+}
+
+// Tax transfers A to X, setting Z and N, if required
+func (cpu *CPU) Tax() {
+	cpu.Registers.X = cpu.setZNFlags(cpu.Registers.A)
+}
+
+// Tay transfers A to Y, setting Z and N, if required
+func (cpu *CPU) Tay() {
+	cpu.Registers.Y = cpu.setZNFlags(cpu.Registers.A)
+}
+
+// Txa transfers X to A, setting Z and N, if required
+func (cpu *CPU) Txa() {
+	cpu.Registers.A = cpu.setZNFlags(cpu.Registers.X)
+}
+
+// Tya transfers Y to A, setting Z and N, if required
+func (cpu *CPU) Tya() {
+	cpu.Registers.A = cpu.setZNFlags(cpu.Registers.Y)
+}
+
+// Tsx transfers SP to X, setting Z and N, if required
+func (cpu *CPU) Tsx() {
+	cpu.Registers.X = cpu.setZNFlags(cpu.Registers.SP)
+}
+
+// Txs transfers X to SP
+func (cpu *CPU) Txs() {
+	cpu.Registers.SP = cpu.Registers.X
+}
+
+// Inx increments X by one, setting Z and N, if required
+func (cpu *CPU) Inx() {
+	cpu.Registers.X = cpu.inc(cpu.Registers.X)
+}
+
+// Iny increments Y by one, setting Z and N, if required
+func (cpu *CPU) Iny() {
+	cpu.Registers.Y = cpu.inc(cpu.Registers.Y)
+}
+
+// Dex decrements X by one, setting Z and N, if required
+func (cpu *CPU) Dex() {
+	cpu.Registers.X = cpu.dec(cpu.Registers.X)
+}
+
+// Dey decrements Y by one, setting Z and N, if required
+func (cpu *CPU) Dey() {
+	cpu.Registers.Y = cpu.dec(cpu.Registers.Y)
+}
+
+// Clc clears C
+func (cpu *CPU) Clc() {
+	cpu.Registers.P &= ^C
+}
+
+// Sec sets C
+func (cpu *CPU) Sec() {
+	cpu.Registers.P |= C
+}
+
+// Cli clears I
+func (cpu *CPU) Cli() {
+	cpu.Registers.P &= ^I
+}
+
+// Sei sets I
+func (cpu *CPU) Sei() {
+	cpu.Registers.P |= I
+}
+
+// Clv clears V
+func (cpu *CPU) Clv() {
+	cpu.Registers.P &= ^V
+}
+
+// Cld clears D
+func (cpu *CPU) Cld() {
+	cpu.Registers.P &= ^D
+}
+
+// Sed sets D
+func (cpu *CPU) Sed() {
+	cpu.Registers.P |= D
+}
+
+// Nop does nothing
+func (cpu *CPU) Nop() {
+}
+
+// Jam halts the CPU until it is reset
+func (cpu *CPU) Jam(opcode OpCode) {
+	cpu.Registers.PC--
+	cpu.jammed = true
+	cpu.jam = opcode
+}
+
+// Brk pushes the address of the byte following its padding byte and P
+// with B set on the stack, sets I and jumps through the IRQ vector
+func (cpu *CPU) Brk() {
+	cpu.interrupt(true)
+}
+
+// Unofficial CPU Instructions
+// ===========================
+
+// Slo shifts memory address left one bit and ors the result into A,
+// setting C, Z and N, if required
+func (cpu *CPU) Slo(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value = cpu.asl(value)
+		cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A | value)
+		return value
+	})
+}
+
+// Rla rotates memory address left one bit and ands the result into A,
+// setting C, Z and N, if required
+func (cpu *CPU) Rla(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value = cpu.rol(value)
+		cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A & value)
+		return value
+	})
+}
+
+// Sre shifts memory address right one bit and exclusive ors the result
+// into A, setting C, Z and N, if required
+func (cpu *CPU) Sre(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value = cpu.lsr(value)
+		cpu.Registers.A = cpu.setZNFlags(cpu.Registers.A ^ value)
+		return value
+	})
+}
+
+// Rra rotates memory address right one bit and adds the result to A,
+// setting C, Z, V and N, if required
+func (cpu *CPU) Rra(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value = cpu.ror(value)
+		cpu.adc(value)
+		return value
+	})
+}
+
+// Dcp decrements memory address by one and compares A with the result,
+// setting C, Z and N, if required
+func (cpu *CPU) Dcp(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value--
+		cpu.compare(cpu.Registers.A, value)
+		return value
+	})
+}
+
+// Isc increments memory address by one and subtracts the result from A,
+// setting C, Z, V and N, if required
+func (cpu *CPU) Isc(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		value++
+		cpu.sbc(value)
+		return value
+	})
+}
+
+// Sax stores A and X in memory address
+func (cpu *CPU) Sax(address uint16) {
+	cpu.write(address, cpu.Registers.A&cpu.Registers.X)
+}
+
+// Lax loads A and X with memory address, setting Z and N, if required
+func (cpu *CPU) Lax(address uint16) {
+	cpu.Registers.A = cpu.setZNFlags(cpu.read(address))
+	cpu.Registers.X = cpu.Registers.A
+}
+
+// Anc ands memory address into A, setting Z and N, if required, and copies
+// N into C
+func (cpu *CPU) Anc(address uint16) {
+	cpu.And(address)
+	cpu.setCFlag(cpu.Registers.P&N != 0)
+}
+
+// Alr ands memory address into A and shifts A right one bit, setting C, Z
+// and N, if required
+func (cpu *CPU) Alr(address uint16) {
+	cpu.Registers.A = cpu.lsr(cpu.Registers.A & cpu.read(address))
+}
+
+// Arr ands memory address into A and rotates A right one bit, taking C
+// from bit 6 and V from bits 6 and 5 of the result. In decimal mode the
+// result is BCD corrected and flags are computed as on the NMOS 6502
+func (cpu *CPU) Arr(address uint16) {
+	value := cpu.Registers.A & cpu.read(address)
+	carry := uint8(cpu.Registers.P & C)
+	result := value>>1 | carry<<7
+
+	if !cpu.decimalMode || cpu.Registers.P&D == 0 {
+		cpu.Registers.A = cpu.setZNFlags(result)
+		cpu.setCFlag(result&0x40 != 0)
+		cpu.setVFlag((result^result<<1)&0x40 != 0)
+		return
+	}
+
+	cpu.setNFlag(carry << 7)
+	cpu.setZFlag(result)
+	cpu.setVFlag((result^value)&0x40 != 0)
+
+	if value&0x0f+value&0x01 > 0x05 {
+		result = result&0xf0 | (result+0x06)&0x0f
+	}
+	if uint16(value&0xf0)+uint16(value&0x10) > 0x50 {
+		result = result&0x0f | (result+0x60)&0xf0
+		cpu.setCFlag(true)
+	} else {
+		cpu.setCFlag(false)
+	}
+	cpu.Registers.A = result
+}
+
+// Axs stores A and X minus memory address in X, setting C, Z and N, if
+// required
+func (cpu *CPU) Axs(address uint16) {
+	value := cpu.read(address)
+	register := cpu.Registers.A & cpu.Registers.X
+	cpu.setCFlag(register >= value)
+	cpu.Registers.X = cpu.setZNFlags(register - value)
+}
+
+// Las ands memory address with SP and stores the result in A, X and SP,
+// setting Z and N, if required
+func (cpu *CPU) Las(address uint16) {
+	value := cpu.setZNFlags(cpu.read(address) & cpu.Registers.SP)
+	cpu.Registers.A = value
+	cpu.Registers.X = value
+	cpu.Registers.SP = value
+}
+
+// Ane stores A or an unstable magic constant, anded with X and memory
+// address, in A, setting Z and N, if required
+func (cpu *CPU) Ane(address uint16) {
+	value := (cpu.Registers.A | 0xee) & cpu.Registers.X & cpu.read(address)
+	cpu.Registers.A = cpu.setZNFlags(value)
+}
+
+// Lxa stores A or an unstable magic constant, anded with memory address, in
+// A and X, setting Z and N, if required
+func (cpu *CPU) Lxa(address uint16) {
+	value := cpu.setZNFlags((cpu.Registers.A | 0xee) & cpu.read(address))
+	cpu.Registers.A = value
+	cpu.Registers.X = value
+}
+
+// sh stores value anded with the high byte of the unindexed address plus
+// one. When indexing crosses a page the stored value also replaces the high
+// byte of the address written to
+func (cpu *CPU) sh(address uint16, index uint8, value uint8) {
+	base := address - uint16(index)
+	value &= uint8(base>>8) + 1
+	if !SamePage(base, address) {
+		address = (uint16(value) << 8) | (address & 0x00ff)
+	}
+	cpu.write(address, value)
+}
+
+// Sha stores A and X and the high byte of the address plus one in memory
+// address
+func (cpu *CPU) Sha(address uint16) {
+	cpu.sh(address, cpu.Registers.Y, cpu.Registers.A&cpu.Registers.X)
+}
+
+// Shx stores X and the high byte of the address plus one in memory address
+func (cpu *CPU) Shx(address uint16) {
+	cpu.sh(address, cpu.Registers.Y, cpu.Registers.X)
+}
+
+// Shy stores Y and the high byte of the address plus one in memory address
+func (cpu *CPU) Shy(address uint16) {
+	cpu.sh(address, cpu.Registers.X, cpu.Registers.Y)
+}
+
+// Tas stores A and X in SP and then SP and the high byte of the address
+// plus one in memory address
+func (cpu *CPU) Tas(address uint16) {
+	cpu.Registers.SP = cpu.Registers.A & cpu.Registers.X
+	cpu.sh(address, cpu.Registers.Y, cpu.Registers.SP)
+}
+
+// 65C02 CPU Instructions
+// ======================
+
+// Bra branches to address unconditionally
+func (cpu *CPU) Bra(address uint16) InstructionStatus {
+	return cpu.branch(address, true)
+}
+
+// Stz stores zero in memory address
+func (cpu *CPU) Stz(address uint16) {
+	cpu.write(address, 0)
+}
+
+// Tsb sets the bits of memory address that are set in A, setting Z if
+// memory address and A have no bits in common
+func (cpu *CPU) Tsb(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		cpu.setZFlag(cpu.Registers.A & value)
+		return value | cpu.Registers.A
+	})
+}
+
+// Trb clears the bits of memory address that are set in A, setting Z if
+// memory address and A have no bits in common
+func (cpu *CPU) Trb(address uint16) {
+	cpu.modify(address, func(value uint8) uint8 {
+		cpu.setZFlag(cpu.Registers.A & value)
+		return value & ^cpu.Registers.A
+	})
+}
+
+// BitImmediate ands A with memory address, setting Z if required. Unlike
+// the other addressing modes of BIT it leaves N and V alone
+func (cpu *CPU) BitImmediate(address uint16) {
+	cpu.setZFlag(cpu.Registers.A & cpu.read(address))
+}
+
+// IncA increments A by one, setting Z and N, if required
+func (cpu *CPU) IncA() {
+	cpu.Registers.A = cpu.inc(cpu.Registers.A)
+}
+
+// DecA decrements A by one, setting Z and N, if required
+func (cpu *CPU) DecA() {
+	cpu.Registers.A = cpu.dec(cpu.Registers.A)
+}
+
+// Phx pushes X on the stack
+func (cpu *CPU) Phx() {
+	cpu.push8(cpu.Registers.X)
+}
+
+// Plx pulls X from the stack, setting Z and N, if required
+func (cpu *CPU) Plx() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.X = cpu.setZNFlags(cpu.pull8())
+}
+
+// Phy pushes Y on the stack
+func (cpu *CPU) Phy() {
+	cpu.push8(cpu.Registers.Y)
+}
+
+// Ply pulls Y from the stack, setting Z and N, if required
+func (cpu *CPU) Ply() {
+	cpu.dummyRead(0x0100 | uint16(cpu.Registers.SP))
+	cpu.Registers.Y = cpu.setZNFlags(cpu.pull8())
 }
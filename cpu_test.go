@@ -0,0 +1,547 @@
+package cpu
+
+import "testing"
+
+type access struct {
+	address uint16
+	value   uint8
+	write   bool
+}
+
+// recordingMemory logs every access made to it
+type recordingMemory struct {
+	*BasicMemory
+	log []access
+}
+
+func newRecordingMemory() *recordingMemory {
+	return &recordingMemory{BasicMemory: NewBasicMemory(DEFAULT_MEMORY_SIZE)}
+}
+
+func (mem *recordingMemory) Read(address uint16) (value uint8) {
+	value = mem.BasicMemory.Read(address)
+	mem.log = append(mem.log, access{address, value, false})
+	return
+}
+
+func (mem *recordingMemory) Write(address uint16, value uint8) (oldValue uint8) {
+	mem.log = append(mem.log, access{address, value, true})
+	return mem.BasicMemory.Write(address, value)
+}
+
+// setupCycleTest prepares an instruction at 0x0200 whose operand points at
+// 0x0380, indexing with index crosses a page when it is 0xff
+func setupCycleTest(cpu *CPU, opcode OpCode, index uint8) {
+	cpu.Registers.PC = 0x0200
+	cpu.Registers.X = index
+	cpu.Registers.Y = index
+	cpu.Registers.P = U | Z | C
+
+	cpu.Memory.Write(0x0200, uint8(opcode))
+	cpu.Memory.Write(0x0201, 0x80)
+	cpu.Memory.Write(0x0202, 0x03)
+	cpu.Memory.Write(0x007f, 0x80)
+	cpu.Memory.Write(0x0080, 0x80)
+	cpu.Memory.Write(0x0081, 0x03)
+}
+
+func TestCycleAccurateCycles(t *testing.T) {
+	for _, variant := range []Variant{NMOS6502, CMOS65C02} {
+		testCycleAccurateCycles(t, variant)
+	}
+}
+
+func testCycleAccurateCycles(t *testing.T, variant Variant) {
+	for o := 0; o < 0x100; o++ {
+		opcode := OpCode(o)
+
+		for _, index := range []uint8{0x00, 0xff} {
+			table := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE),
+				WithVariant(variant), WithIllegalOpcodes())
+			accurate := NewCPU(newRecordingMemory(),
+				WithVariant(variant), WithIllegalOpcodes(), WithCycleAccuracy(nil))
+
+			if table.Instructions.opcodes[opcode] == nil {
+				continue
+			}
+
+			setupCycleTest(table, opcode, index)
+			setupCycleTest(accurate, opcode, index)
+			accurate.Memory.(*recordingMemory).log = nil
+
+			expected, _ := table.Execute()
+			cycles, _ := accurate.Execute()
+
+			if expected == 0 {
+				continue
+			}
+
+			if cycles != expected {
+				t.Errorf("%v opcode %#02x with index %#02x took %v cycles not %v",
+					variant, o, index, cycles, expected)
+			}
+
+			if log := accurate.Memory.(*recordingMemory).log; len(log) != int(cycles) {
+				t.Errorf("%v opcode %#02x with index %#02x made %v bus accesses in %v cycles",
+					variant, o, index, len(log), cycles)
+			}
+
+			if table.Registers != accurate.Registers {
+				t.Errorf("%v opcode %#02x with index %#02x registers %+v != %+v",
+					variant, o, index, accurate.Registers, table.Registers)
+			}
+
+			if accurate.Cycles() != uint64(cycles) || table.Cycles() != uint64(expected) {
+				t.Errorf("%v opcode %#02x did not count its cycles", variant, o)
+			}
+		}
+	}
+}
+
+func TestCycleAccurateBranches(t *testing.T) {
+	for _, b := range []struct {
+		pc     uint16
+		offset uint8
+		cycles uint16
+	}{
+		{0x0200, 0x10, 3},
+		{0x0200, 0xf0, 4},
+		{0x02f0, 0x10, 4},
+	} {
+		cpu := NewCPU(newRecordingMemory(), WithCycleAccuracy(nil))
+		cpu.Registers.PC = b.pc
+
+		cpu.Memory.Write(b.pc, 0xd0)
+		cpu.Memory.Write(b.pc+1, b.offset)
+
+		cycles, _ := cpu.Execute()
+
+		if cycles != b.cycles {
+			t.Errorf("BNE at %#04x by %#02x took %v cycles not %v", b.pc, b.offset, cycles, b.cycles)
+		}
+	}
+}
+
+func TestCycleAccurateTick(t *testing.T) {
+	ticks := 0
+	cpu := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithCycleAccuracy(func() {
+		ticks++
+	}))
+
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0xee)
+	cpu.Memory.Write(0x0201, 0x80)
+	cpu.Memory.Write(0x0202, 0x03)
+
+	cycles, _ := cpu.Execute()
+
+	if ticks != 6 || cycles != 6 {
+		t.Errorf("INC absolute ticked %v times in %v cycles, not 6", ticks, cycles)
+	}
+}
+
+func TestCycleAccurateDummyWrite(t *testing.T) {
+	mem := newRecordingMemory()
+	cpu := NewCPU(mem, WithCycleAccuracy(nil))
+
+	cpu.Registers.X = 0x01
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0xfe)
+	cpu.Memory.Write(0x0201, 0xff)
+	cpu.Memory.Write(0x0202, 0x03)
+	cpu.Memory.Write(0x0400, 0x41)
+	mem.log = nil
+
+	cpu.Execute()
+
+	expected := []access{
+		{0x0200, 0xfe, false},
+		{0x0201, 0xff, false},
+		{0x0202, 0x03, false},
+		{0x0300, 0x00, false},
+		{0x0400, 0x41, false},
+		{0x0400, 0x41, true},
+		{0x0400, 0x42, true},
+	}
+
+	if len(mem.log) != len(expected) {
+		t.Fatalf("Bus log %+v != %+v", mem.log, expected)
+	}
+
+	for i := range expected {
+		if mem.log[i] != expected[i] {
+			t.Errorf("Cycle %v %+v != %+v", i+1, mem.log[i], expected[i])
+		}
+	}
+}
+
+func TestCycleAccurateJsr(t *testing.T) {
+	mem := newRecordingMemory()
+	cpu := NewCPU(mem, WithCycleAccuracy(nil))
+
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0x20)
+	cpu.Memory.Write(0x0201, 0x34)
+	cpu.Memory.Write(0x0202, 0x12)
+	mem.log = nil
+
+	cpu.Execute()
+
+	expected := []access{
+		{0x0200, 0x20, false},
+		{0x0201, 0x34, false},
+		{0x01fd, 0x00, false},
+		{0x01fd, 0x02, true},
+		{0x01fc, 0x02, true},
+		{0x0202, 0x12, false},
+	}
+
+	if len(mem.log) != len(expected) {
+		t.Fatalf("Bus log %+v != %+v", mem.log, expected)
+	}
+
+	for i := range expected {
+		if mem.log[i] != expected[i] {
+			t.Errorf("Cycle %v %+v != %+v", i+1, mem.log[i], expected[i])
+		}
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+}
+
+// setupInterruptTest fills the program at 0x0200 and the handlers at 0x3000
+// for NMI and 0x4000 for IRQ with NOPs
+func setupInterruptTest(options ...Option) *CPU {
+	cpu := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), options...)
+	for _, page := range []uint16{0x0200, 0x3000, 0x4000} {
+		for address := page; address < page+0x0100; address++ {
+			cpu.Memory.Write(address, 0xea)
+		}
+	}
+	cpu.Memory.Write(0xfffa, 0x00)
+	cpu.Memory.Write(0xfffb, 0x30)
+	cpu.Memory.Write(0xfffe, 0x00)
+	cpu.Memory.Write(0xffff, 0x40)
+	cpu.Registers.PC = 0x0200
+	cpu.Registers.P = U
+	return cpu
+}
+
+// interruptTestModes runs test in both the table and cycle-accurate modes
+func interruptTestModes(t *testing.T, test func(t *testing.T, options ...Option)) {
+	t.Run("table", func(t *testing.T) {
+		test(t)
+	})
+	t.Run("cycle-accurate", func(t *testing.T) {
+		test(t, WithCycleAccuracy(nil))
+	})
+}
+
+func TestNmiPriority(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Irq.Assert(IrqExternal)
+		cpu.Nmi = true
+
+		cpu.Execute()
+		cycles, _ := cpu.Execute()
+
+		if cycles != 7+2 {
+			t.Errorf("Cycles is %v not 9", cycles)
+		}
+
+		if cpu.Registers.PC != 0x3001 {
+			t.Errorf("Register PC 0x3001 != %#04x", cpu.Registers.PC)
+		}
+
+		if Status(cpu.Memory.Read(0x01fb))&B != 0 {
+			t.Error("B flag was pushed")
+		}
+	})
+}
+
+func TestNmiEdgeTriggered(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Memory.Write(0x3000, 0xe8) // INX
+		cpu.Memory.Write(0x3001, 0x40) // RTI
+		cpu.Nmi = true
+
+		for i := 0; i < 6; i++ {
+			cpu.Execute()
+		}
+
+		if cpu.Registers.X != 1 {
+			t.Errorf("NMI was serviced %v times while the line was held", cpu.Registers.X)
+		}
+
+		cpu.Nmi = false
+		cpu.Execute()
+		cpu.Nmi = true
+		cpu.Execute()
+		cpu.Execute()
+
+		if cpu.Registers.X != 2 {
+			t.Errorf("NMI was not serviced on a new edge")
+		}
+	})
+}
+
+func TestIrqLevelTriggered(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Memory.Write(0x4000, 0xe8) // INX
+		cpu.Memory.Write(0x4001, 0x40) // RTI
+		cpu.Irq.Assert(IrqExternal)
+
+		for i := 0; i < 8; i++ {
+			cpu.Execute()
+		}
+
+		if !cpu.Irq.Asserted() {
+			t.Error("IRQ line was released by the CPU")
+		}
+
+		if cpu.Registers.X < 2 {
+			t.Errorf("IRQ was serviced %v times while the line was held", cpu.Registers.X)
+		}
+	})
+}
+
+func TestIrqMasked(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Registers.P |= I
+		cpu.Irq.Assert(IrqExternal)
+
+		for i := 0; i < 4; i++ {
+			cpu.Execute()
+		}
+
+		if cpu.Registers.PC != 0x0204 {
+			t.Errorf("Register PC 0x0204 != %#04x", cpu.Registers.PC)
+		}
+	})
+}
+
+func TestCliDelaysIrq(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Registers.P |= I
+		cpu.Memory.Write(0x0200, 0x58) // CLI
+		cpu.Irq.Assert(IrqExternal)
+
+		cpu.Execute()
+		cpu.Execute()
+
+		if cpu.Registers.PC != 0x0202 {
+			t.Errorf("IRQ was serviced right after CLI, PC is %#04x", cpu.Registers.PC)
+		}
+
+		cpu.Execute()
+
+		if cpu.Registers.PC != 0x4001 {
+			t.Errorf("IRQ was not serviced after the instruction following CLI, PC is %#04x",
+				cpu.Registers.PC)
+		}
+	})
+}
+
+func TestSeiDelaysMask(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Memory.Write(0x0200, 0x78) // SEI
+		cpu.Irq.Assert(IrqExternal)
+
+		cpu.Execute()
+		cpu.Execute()
+
+		if cpu.Registers.PC != 0x4001 {
+			t.Errorf("IRQ was not serviced after SEI, PC is %#04x", cpu.Registers.PC)
+		}
+
+		if Status(cpu.Memory.Read(0x01fb))&I == 0 {
+			t.Error("I flag was not pushed")
+		}
+	})
+}
+
+func TestRtiDoesNotDelayIrq(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := setupInterruptTest(options...)
+		cpu.Registers.P |= I
+		cpu.Memory.Write(0x0200, 0x40) // RTI
+		cpu.Memory.Write(0x01fd, 0x02) // to 0x0280 with I clear
+		cpu.Memory.Write(0x01fc, 0x80)
+		cpu.Memory.Write(0x01fb, uint8(U))
+		cpu.Registers.SP = 0xfa
+		cpu.Irq.Assert(IrqExternal)
+
+		cpu.Execute()
+		cpu.Execute()
+
+		if cpu.Registers.PC != 0x4001 {
+			t.Errorf("IRQ was not serviced right after RTI, PC is %#04x", cpu.Registers.PC)
+		}
+	})
+}
+
+func TestNmiHijacksBrk(t *testing.T) {
+	var cpu *CPU
+	cycles := 0
+	cpu = setupInterruptTest(WithCycleAccuracy(func() {
+		cycles++
+		// assert NMI during the cycle BRK pushes PCH
+		if cycles == 3 {
+			cpu.Nmi = true
+		}
+	}))
+	cpu.Memory.Write(0x0200, 0x00)
+
+	cpu.Execute()
+
+	if cpu.Registers.PC != 0x3000 {
+		t.Errorf("Register PC 0x3000 != %#04x", cpu.Registers.PC)
+	}
+
+	if Status(cpu.Memory.Read(0x01fb))&B == 0 {
+		t.Error("B flag was not pushed")
+	}
+
+	if cpu.Memory.Read(0x01fd) != 0x02 || cpu.Memory.Read(0x01fc) != 0x02 {
+		t.Error("Return address is not 0x0202")
+	}
+
+	cpu.Execute()
+
+	if cpu.Registers.PC != 0x3001 {
+		t.Errorf("NMI was serviced twice, PC is %#04x", cpu.Registers.PC)
+	}
+}
+
+func TestNmiDuringLastCycleIsDelayed(t *testing.T) {
+	var cpu *CPU
+	cycles := 0
+	cpu = setupInterruptTest(WithCycleAccuracy(func() {
+		cycles++
+		// assert NMI on the last cycle of the first NOP
+		if cycles == 2 {
+			cpu.Nmi = true
+		}
+	}))
+
+	cpu.Execute()
+	cpu.Execute()
+
+	if cpu.Registers.PC != 0x0202 {
+		t.Errorf("NMI was not delayed by an instruction, PC is %#04x", cpu.Registers.PC)
+	}
+
+	cpu.Execute()
+
+	if cpu.Registers.PC != 0x3001 {
+		t.Errorf("NMI was not serviced, PC is %#04x", cpu.Registers.PC)
+	}
+}
+
+func TestPowerOn(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+		mem.PowerOnFill(FillOnes())
+		cpu := NewCPU(mem, options...)
+		cpu.Registers.A = 0x12
+		cpu.Registers.P = C | U
+
+		cpu.PowerOn()
+
+		if cpu.Registers.A != 0x00 {
+			t.Errorf("Register A 0x00 != %#02x", cpu.Registers.A)
+		}
+
+		if cpu.Registers.SP != 0xfd {
+			t.Errorf("Register SP 0xfd != %#02x", cpu.Registers.SP)
+		}
+
+		if cpu.Registers.P != I|U {
+			t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, I|U)
+		}
+
+		if cpu.Registers.PC != 0xffff {
+			t.Errorf("Register PC 0xffff != %#04x", cpu.Registers.PC)
+		}
+
+		if cpu.Cycles() != 7 {
+			t.Errorf("Cycles is %v not 7", cpu.Cycles())
+		}
+	})
+}
+
+func TestSoftReset(t *testing.T) {
+	interruptTestModes(t, func(t *testing.T, options ...Option) {
+		cpu := NewCPU(newRecordingMemory(), options...)
+		cpu.PowerOn()
+		cpu.Memory.Write(0x0300, 0x12)
+		cpu.Memory.Write(0xfffc, 0x00)
+		cpu.Memory.Write(0xfffd, 0x80)
+		cpu.Registers.A = 0x34
+		cpu.Registers.SP = 0xf0
+		cpu.Registers.P = C | U
+		cpu.Memory.(*recordingMemory).log = nil
+
+		cpu.SoftReset()
+
+		if cpu.Memory.Read(0x0300) != 0x12 {
+			t.Error("Memory was cleared")
+		}
+
+		if cpu.Registers.A != 0x34 {
+			t.Errorf("Register A 0x34 != %#02x", cpu.Registers.A)
+		}
+
+		if cpu.Registers.SP != 0xed {
+			t.Errorf("Register SP 0xed != %#02x", cpu.Registers.SP)
+		}
+
+		if cpu.Registers.P != C|I|U {
+			t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, C|I|U)
+		}
+
+		if cpu.Registers.PC != 0x8000 {
+			t.Errorf("Register PC 0x8000 != %#04x", cpu.Registers.PC)
+		}
+
+		for _, a := range cpu.Memory.(*recordingMemory).log {
+			if a.write {
+				t.Errorf("Reset wrote %#02x to %#04x", a.value, a.address)
+			}
+		}
+	})
+}
+
+func TestSaveState(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	mem.Write(0x0200, 0xe8) // INX
+	mem.Write(0x0201, 0xe8)
+
+	cpu := NewCPU(mem)
+	cpu.Registers.PC = 0x0200
+	state := cpu.SaveState()
+
+	cpu.Irq.Assert(IrqExternal)
+	for i := 0; i < 2; i++ {
+		cpu.Execute()
+	}
+	cpu.RestoreState(state)
+
+	if cpu.Registers != state.Registers || cpu.Registers.PC != 0x0200 {
+		t.Errorf("Registers %+v != %+v", cpu.Registers, state.Registers)
+	}
+	if cpu.Cycles() != 0 || cpu.Irq.Asserted() {
+		t.Errorf("Cycles %d, IRQ asserted %v", cpu.Cycles(), cpu.Irq.Asserted())
+	}
+}
@@ -0,0 +1,383 @@
+// Package debugger controls a CPU with breakpoints, watchpoints and
+// stepping
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/mpicard/gones"
+)
+
+// Access is a kind of memory access watched by a Watchpoint
+type Access uint8
+
+const (
+	// Read of memory, including operand and opcode fetches
+	Read Access = 1 << iota
+	// Write to memory
+	Write
+	// Execute an instruction, checked before it runs
+	Execute
+)
+
+func (a Access) String() (s string) {
+	for _, access := range []struct {
+		access Access
+		name   string
+	}{{Read, "r"}, {Write, "w"}, {Execute, "x"}} {
+		if a&access.access != 0 {
+			s += access.name
+		} else {
+			s += "-"
+		}
+	}
+	return
+}
+
+// Breakpoint stops the CPU before it executes the instruction at Address,
+// if Condition is nil or evaluates to non-zero. Hits counts the times
+// Address was reached, whether the condition was true or not
+type Breakpoint struct {
+	ID        int
+	Address   uint16
+	Condition *Condition
+	Hits      int
+}
+
+// Watchpoint stops the CPU after an instruction accesses memory between
+// Start and End inclusive, or before it executes an instruction there
+type Watchpoint struct {
+	ID     int
+	Start  uint16
+	End    uint16
+	Access Access
+	Hits   int
+}
+
+// stops returns true if the CPU should stop at b, which is when it has no
+// condition or the condition is non-zero. A condition that can not be
+// evaluated, such as one that divides by zero, stops with an error
+func (d *Debugger) stops(b *Breakpoint) (bool, error) {
+	if b.Condition == nil {
+		return true, nil
+	}
+	value, err := b.Condition.Eval(&d.CPU.Registers, d.mem, b.Hits)
+	if err != nil {
+		return true, fmt.Errorf("breakpoint %d at $%04X: %v", b.ID, b.Address, err)
+	}
+	return value != 0, nil
+}
+
+func (w *Watchpoint) contains(address uint16) bool {
+	return address >= w.Start && address <= w.End
+}
+
+// BreakpointError is returned when the CPU stops at a Breakpoint
+type BreakpointError struct {
+	Breakpoint *Breakpoint
+}
+
+func (b *BreakpointError) Error() string {
+	if b.Breakpoint.Condition != nil {
+		return fmt.Sprintf("breakpoint %d at $%04X if %v",
+			b.Breakpoint.ID, b.Breakpoint.Address, b.Breakpoint.Condition)
+	}
+	return fmt.Sprintf("breakpoint %d at $%04X", b.Breakpoint.ID, b.Breakpoint.Address)
+}
+
+// WatchpointError is returned when the CPU stops at a Watchpoint
+type WatchpointError struct {
+	Watchpoint *Watchpoint
+	Address    uint16
+	Value      uint8
+	Access     Access
+}
+
+func (w *WatchpointError) Error() string {
+	return fmt.Sprintf("watchpoint %d %v at $%04X = $%02X",
+		w.Watchpoint.ID, w.Access, w.Address, w.Value)
+}
+
+// ErrInterrupted is returned when running is stopped by Interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// Debugger wraps a CPU. It replaces the CPU's Memory with one that reports
+// accesses to the watchpoints, Close puts the original one back
+type Debugger struct {
+	CPU         *cpu.CPU
+	mem         cpu.Memory
+	breakpoints map[int]*Breakpoint
+	watchpoints map[int]*Watchpoint
+	nextID      int
+	hit         *WatchpointError
+	history     *history
+	interrupted int32
+}
+
+// New attaches a Debugger to c
+func New(c *cpu.CPU) *Debugger {
+	d := &Debugger{
+		CPU:         c,
+		mem:         c.Memory,
+		breakpoints: make(map[int]*Breakpoint),
+		watchpoints: make(map[int]*Watchpoint),
+		nextID:      1,
+	}
+	c.Memory = &watchMemory{Memory: c.Memory, debugger: d}
+	return d
+}
+
+// Close detaches the Debugger from the CPU
+func (d *Debugger) Close() {
+	d.CPU.Memory = d.mem
+}
+
+// Memory returns the CPU's memory without the watchpoints, reading it does
+// not stop the CPU
+func (d *Debugger) Memory() cpu.Memory {
+	return d.mem
+}
+
+// Peek reads memory without side effects or stopping the CPU
+func (d *Debugger) Peek(address uint16) uint8 {
+	return cpu.Peek(d.mem, address)
+}
+
+// Poke writes memory without side effects or stopping the CPU
+func (d *Debugger) Poke(address uint16, value uint8) (oldValue uint8) {
+	return cpu.Poke(d.mem, address, value)
+}
+
+// Break adds a breakpoint at address
+func (d *Debugger) Break(address uint16) *Breakpoint {
+	b := &Breakpoint{ID: d.nextID, Address: address}
+	d.breakpoints[b.ID] = b
+	d.nextID++
+	return b
+}
+
+// BreakIf adds a breakpoint at address that stops when condition is true,
+// see Condition
+func (d *Debugger) BreakIf(address uint16, condition string) (*Breakpoint, error) {
+	c, err := ParseCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	b := d.Break(address)
+	b.Condition = c
+	return b, nil
+}
+
+// Watch adds a watchpoint for access to memory from start to end inclusive
+func (d *Debugger) Watch(start, end uint16, access Access) *Watchpoint {
+	w := &Watchpoint{ID: d.nextID, Start: start, End: end, Access: access}
+	d.watchpoints[w.ID] = w
+	d.nextID++
+	return w
+}
+
+// Delete removes the breakpoint or watchpoint with id, returning false if
+// there is none
+func (d *Debugger) Delete(id int) bool {
+	if _, ok := d.breakpoints[id]; ok {
+		delete(d.breakpoints, id)
+		return true
+	}
+	if _, ok := d.watchpoints[id]; ok {
+		delete(d.watchpoints, id)
+		return true
+	}
+	return false
+}
+
+// Breakpoints returns the breakpoints ordered by ID
+func (d *Debugger) Breakpoints() (result []*Breakpoint) {
+	for _, b := range d.breakpoints {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return
+}
+
+// Watchpoints returns the watchpoints ordered by ID
+func (d *Debugger) Watchpoints() (result []*Watchpoint) {
+	for _, w := range d.watchpoints {
+		result = append(result, w)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return
+}
+
+// access is called by watchMemory for every read and write. Only the first
+// hit of an instruction is reported
+func (d *Debugger) access(address uint16, value uint8, access Access) {
+	if d.hit != nil {
+		return
+	}
+	for _, w := range d.Watchpoints() {
+		if w.Access&access != 0 && w.contains(address) {
+			w.Hits++
+			d.hit = &WatchpointError{w, address, value, access}
+			return
+		}
+	}
+}
+
+// Interrupt stops Run, RunTo, StepOver or StepOut before the next
+// instruction. It can be called from another goroutine
+func (d *Debugger) Interrupt() {
+	atomic.StoreInt32(&d.interrupted, 1)
+}
+
+// stop returns ErrInterrupted, or the breakpoint or execute watchpoint at
+// PC, if any
+func (d *Debugger) stop() error {
+	if atomic.SwapInt32(&d.interrupted, 0) != 0 {
+		return ErrInterrupted
+	}
+
+	pc := d.CPU.Registers.PC
+	for _, b := range d.Breakpoints() {
+		if b.Address != pc {
+			continue
+		}
+		b.Hits++
+		if stop, err := d.stops(b); err != nil {
+			return err
+		} else if stop {
+			return &BreakpointError{b}
+		}
+	}
+	for _, w := range d.Watchpoints() {
+		if w.Access&Execute != 0 && w.contains(pc) {
+			w.Hits++
+			return &WatchpointError{w, pc, d.Peek(pc), Execute}
+		}
+	}
+	return nil
+}
+
+// Step executes one instruction, ignoring breakpoints at PC. It returns a
+// WatchpointError if the instruction accessed watched memory
+func (d *Debugger) Step() error {
+	d.hit = nil
+	d.begin()
+	if _, err := d.CPU.Execute(); err != nil {
+		return err
+	}
+	if d.hit != nil {
+		return d.hit
+	}
+	return nil
+}
+
+// run steps until done returns true for the instruction just executed, or
+// a breakpoint or watchpoint is hit. The first instruction always executes,
+// so that running again continues from a breakpoint
+func (d *Debugger) run(done func(inst *cpu.Instruction) bool) error {
+	for first := true; ; first = false {
+		if !first {
+			if err := d.stop(); err != nil {
+				return err
+			}
+		}
+
+		inst := d.CPU.Instructions.Lookup(cpu.OpCode(d.Peek(d.CPU.Registers.PC)))
+		if err := d.Step(); err != nil {
+			return err
+		}
+		if done != nil && done(inst) {
+			return nil
+		}
+	}
+}
+
+// Run executes instructions until a breakpoint or watchpoint is hit, or
+// Execute returns an error
+func (d *Debugger) Run() error {
+	return d.run(nil)
+}
+
+// RunTo runs until PC is address
+func (d *Debugger) RunTo(address uint16) error {
+	return d.run(func(*cpu.Instruction) bool {
+		return d.CPU.Registers.PC == address
+	})
+}
+
+// StepOver steps, except that a JSR runs until the subroutine returns
+func (d *Debugger) StepOver() error {
+	reg := d.CPU.Registers
+	inst := d.CPU.Instructions.Lookup(cpu.OpCode(d.Peek(reg.PC)))
+	if inst == nil || inst.Mneumonic != "JSR" {
+		return d.Step()
+	}
+
+	ret := reg.PC + 3
+	return d.run(func(*cpu.Instruction) bool {
+		return d.CPU.Registers.PC == ret && d.CPU.Registers.SP == reg.SP
+	})
+}
+
+// StepOut runs until the current subroutine returns with RTS, or RTI
+// returns from the current interrupt handler
+func (d *Debugger) StepOut() error {
+	sp := d.CPU.Registers.SP
+	return d.run(func(inst *cpu.Instruction) bool {
+		if inst == nil || (inst.Mneumonic != "RTS" && inst.Mneumonic != "RTI") {
+			return false
+		}
+		return d.CPU.Registers.SP > sp
+	})
+}
+
+// watchMemory reports every access to the Debugger
+type watchMemory struct {
+	cpu.Memory
+	debugger *Debugger
+}
+
+func (mem *watchMemory) Read(address uint16) (value uint8) {
+	value = mem.Memory.Read(address)
+	mem.debugger.access(address, value, Read)
+	return
+}
+
+func (mem *watchMemory) Write(address uint16, value uint8) (oldValue uint8) {
+	// ROM and write-only devices don't return what was there, so the
+	// history takes it from Peek. Memory that can't be peeked at isn't
+	// read, as that could have side effects
+	debug, ok := mem.Memory.(cpu.DebugMemory)
+	if mem.debugger.history == nil || !ok {
+		oldValue = mem.Memory.Write(address, value)
+	} else {
+		old := debug.Peek(address)
+		oldValue = mem.Memory.Write(address, value)
+		// Writes dropped by ROM have nothing to undo
+		if after := debug.Peek(address); after != old || after == value {
+			mem.debugger.written(address, old, value)
+		}
+	}
+	mem.debugger.access(address, value, Write)
+	return
+}
+
+func (mem *watchMemory) Fetch(address uint16) (value uint8) {
+	value = cpu.Fetch(mem.Memory, address)
+	mem.debugger.access(address, value, Read)
+	return
+}
+
+// Peek and Poke are not watched
+
+func (mem *watchMemory) Peek(address uint16) uint8 {
+	return cpu.Peek(mem.Memory, address)
+}
+
+func (mem *watchMemory) Poke(address uint16, value uint8) uint8 {
+	return cpu.Poke(mem.Memory, address, value)
+}
@@ -0,0 +1,236 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/asm"
+)
+
+const program = `
+	.org $0200
+start:	LDX #0
+	JSR sub
+	STA $10
+	LDA $20
+	BRK
+
+sub:	LDA #$42
+	JSR inner
+	RTS
+
+inner:	INX
+	RTS
+`
+
+func setup(t *testing.T) (*Debugger, map[string]uint16) {
+	p, err := asm.Assemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cpu.NewCPU(cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE))
+	p.Load(c.Memory)
+	c.Registers.PC = 0x0200
+	return New(c), p.Labels
+}
+
+func TestStep(t *testing.T) {
+	d, labels := setup(t)
+
+	for _, expected := range []uint16{0x0202, labels["sub"], labels["sub"] + 2, labels["inner"]} {
+		if err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if d.CPU.Registers.PC != expected {
+			t.Errorf("PC %#04x != %#04x", d.CPU.Registers.PC, expected)
+		}
+	}
+
+	if err := d.StepOut(); err != nil {
+		t.Fatal(err)
+	}
+	if d.CPU.Registers.PC != labels["sub"]+5 {
+		t.Errorf("StepOut PC %#04x != %#04x", d.CPU.Registers.PC, labels["sub"]+5)
+	}
+
+	if err := d.StepOut(); err != nil {
+		t.Fatal(err)
+	}
+	if d.CPU.Registers.PC != 0x0205 {
+		t.Errorf("StepOut PC %#04x != 0x0205", d.CPU.Registers.PC)
+	}
+}
+
+func TestStepOver(t *testing.T) {
+	d, _ := setup(t)
+
+	for _, expected := range []uint16{0x0202, 0x0205, 0x0207} {
+		if err := d.StepOver(); err != nil {
+			t.Fatal(err)
+		}
+		if d.CPU.Registers.PC != expected {
+			t.Errorf("PC %#04x != %#04x", d.CPU.Registers.PC, expected)
+		}
+	}
+
+	if d.CPU.Registers.A != 0x42 || d.CPU.Registers.X != 1 {
+		t.Errorf("Subroutine did not run, A %#02x X %#02x", d.CPU.Registers.A, d.CPU.Registers.X)
+	}
+}
+
+func TestRunTo(t *testing.T) {
+	d, labels := setup(t)
+
+	if err := d.RunTo(labels["inner"]); err != nil {
+		t.Fatal(err)
+	}
+	if d.CPU.Registers.PC != labels["inner"] {
+		t.Errorf("PC %#04x != %#04x", d.CPU.Registers.PC, labels["inner"])
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	d, labels := setup(t)
+	b := d.Break(labels["inner"])
+	d.Break(0x0205)
+
+	err := d.Run()
+	if e, ok := err.(*BreakpointError); !ok || e.Breakpoint != b {
+		t.Fatalf("Run returned %v not breakpoint %d", err, b.ID)
+	}
+	if d.CPU.Registers.PC != labels["inner"] || d.CPU.Registers.X != 0 {
+		t.Errorf("Stopped at PC %#04x X %#02x", d.CPU.Registers.PC, d.CPU.Registers.X)
+	}
+
+	err = d.Run()
+	if e, ok := err.(*BreakpointError); !ok || e.Breakpoint.Address != 0x0205 {
+		t.Fatalf("Run returned %v not the breakpoint at 0x0205", err)
+	}
+
+	if !d.Delete(b.ID) || d.Delete(b.ID) {
+		t.Error("Delete did not remove the breakpoint once")
+	}
+	if len(d.Breakpoints()) != 1 || b.Hits != 1 {
+		t.Errorf("Breakpoints %v, hits %d", d.Breakpoints(), b.Hits)
+	}
+}
+
+func TestWatchpoint(t *testing.T) {
+	for _, w := range []struct {
+		start, end uint16
+		access     Access
+		pc         uint16
+		address    uint16
+		value      uint8
+	}{
+		{0x0010, 0x0010, Write, 0x0207, 0x0010, 0x42},
+		{0x0018, 0x0020, Read, 0x0209, 0x0020, 0x00},
+		{0x0010, 0x0020, Read | Write, 0x0207, 0x0010, 0x42},
+		{0x0204, 0x0206, Execute, 0x0205, 0x0205, 0x85},
+	} {
+		d, _ := setup(t)
+		d.Watch(w.start, w.end, w.access)
+
+		err := d.Run()
+		e, ok := err.(*WatchpointError)
+		if !ok {
+			t.Errorf("Watch %v returned %v", w.access, err)
+			continue
+		}
+
+		if d.CPU.Registers.PC != w.pc || e.Address != w.address || e.Value != w.value {
+			t.Errorf("Watch %v stopped at PC %#04x on %v", w.access, d.CPU.Registers.PC, e)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	d, _ := setup(t)
+	d.Watch(0x0000, 0xffff, Read)
+	d.Close()
+
+	if err := d.Step(); err != nil {
+		t.Errorf("Closed debugger returned %v", err)
+	}
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	d, labels := setup(t)
+	_, err := d.BreakIf(labels["inner"], "A == $42 && X == 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.Run().(*BreakpointError); !ok || d.CPU.Registers.PC != labels["inner"] {
+		t.Fatalf("Did not stop at %#04x", labels["inner"])
+	}
+
+	if _, err = d.BreakIf(0x0200, "A =="); err == nil {
+		t.Error("BreakIf accepted a bad condition")
+	}
+
+	d, labels = setup(t)
+	d.BreakIf(labels["inner"], "1 / X")
+	err = d.Run()
+	if _, ok := err.(*BreakpointError); ok || err == nil || d.CPU.Registers.PC != labels["inner"] {
+		t.Errorf("Condition dividing by zero returned %v", err)
+	}
+}
+
+func TestHitCount(t *testing.T) {
+	p, err := asm.Assemble(`
+	.org $0200
+	LDX #0
+loop:	INX
+	CPX #10
+	BNE loop
+	BRK
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cpu.NewCPU(cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE))
+	p.Load(c.Memory)
+	c.Registers.PC = 0x0200
+	d := New(c)
+
+	b, err := d.BreakIf(p.Labels["loop"], "hitcount % 3 == 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, x := range []uint8{2, 5, 8} {
+		if _, ok := d.Run().(*BreakpointError); !ok || c.Registers.X != x {
+			t.Errorf("Stopped with X %#02x not %#02x", c.Registers.X, x)
+		}
+	}
+	if b.Hits != 9 {
+		t.Errorf("Hits %d != 9", b.Hits)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	d, _ := setup(t)
+	d.Interrupt()
+
+	if err := d.Run(); err != ErrInterrupted || d.CPU.Registers.PC != 0x0202 {
+		t.Errorf("Run returned %v at PC %#04x", err, d.CPU.Registers.PC)
+	}
+}
+
+func TestPeekPoke(t *testing.T) {
+	d, _ := setup(t)
+	d.Watch(0x0000, 0xffff, Read|Write)
+	d.Record(100, 10)
+
+	d.Poke(0x0010, 0x12)
+	if d.Peek(0x0010) != 0x12 || d.CPU.Memory.(cpu.DebugMemory).Peek(0x0010) != 0x12 {
+		t.Error("Poke did not write memory")
+	}
+
+	if d.hit != nil || d.History() != 0 {
+		t.Errorf("Peek and Poke were watched or recorded")
+	}
+}
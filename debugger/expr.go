@@ -0,0 +1,100 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/expr"
+)
+
+// Condition is a parsed breakpoint condition, e.g.
+//
+//	A == $40 && [$0300] > 3 && P.C
+//	hitcount % 10 == 0
+//
+// The syntax is that of package expr. The names are the registers A, X, Y,
+// SP, PC and P, the status flags C, Z, I, D, B, V and N, also written P.C
+// etc, which are 0 or 1, and hitcount, the number of times the breakpoint
+// has been reached including this one. Names are not case sensitive and
+// [addr] reads memory with Peek
+type Condition struct {
+	expr *expr.Expr
+}
+
+// env is what a Condition is evaluated against
+type env struct {
+	reg  *cpu.Registers
+	mem  cpu.Memory
+	hits int
+}
+
+// ParseCondition parses a condition
+func ParseCondition(text string) (*Condition, error) {
+	e, err := expr.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range e.Symbols() {
+		if _, err = (&env{reg: &cpu.Registers{}}).Symbol(name); err != nil {
+			return nil, fmt.Errorf("unknown name %q in condition %q", name, text)
+		}
+	}
+	return &Condition{e}, nil
+}
+
+// Eval evaluates the condition, memory is read from mem with Peek
+func (c *Condition) Eval(reg *cpu.Registers, mem cpu.Memory, hits int) (int, error) {
+	return c.expr.Eval(&env{reg, mem, hits})
+}
+
+func (c *Condition) String() string {
+	return c.expr.String()
+}
+
+func (e *env) Symbol(name string) (int, error) {
+	switch name = strings.ToUpper(name); name {
+	case "A":
+		return int(e.reg.A), nil
+	case "X":
+		return int(e.reg.X), nil
+	case "Y":
+		return int(e.reg.Y), nil
+	case "SP":
+		return int(e.reg.SP), nil
+	case "PC":
+		return int(e.reg.PC), nil
+	case "P":
+		return int(e.reg.P), nil
+	case "HITCOUNT":
+		return e.hits, nil
+	}
+
+	flag, ok := flags[strings.TrimPrefix(name, "P.")]
+	if !ok {
+		return 0, expr.UndefinedError(name)
+	}
+	if e.reg.P&flag != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (e *env) PC() int {
+	return int(e.reg.PC)
+}
+
+func (e *env) Read(address uint16) (uint8, error) {
+	return cpu.Peek(e.mem, address), nil
+}
+
+var flags = map[string]cpu.Status{
+	"C": cpu.C,
+	"Z": cpu.Z,
+	"I": cpu.I,
+	"D": cpu.D,
+	"B": cpu.B,
+	"V": cpu.V,
+	"N": cpu.N,
+}
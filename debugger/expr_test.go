@@ -0,0 +1,67 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/mpicard/gones"
+)
+
+func TestCondition(t *testing.T) {
+	reg := cpu.Registers{A: 0x40, X: 0x02, Y: 0xff, P: cpu.C | cpu.N, SP: 0xfd, PC: 0xc000}
+	mem := cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE)
+	mem.Write(0x0300, 0x05)
+	mem.Write(0x0302, 0x07)
+
+	for _, c := range []struct {
+		text  string
+		value int
+	}{
+		{"A == $40 && [$0300] > 3 && P.C", 1},
+		{"a == 64 && [$300] > 5", 0},
+		{"C && N && !Z && !p.v", 1},
+		{"P", 0x81},
+		{"[$0300 + X]", 0x07},
+		{"PC >> 8 | SP << 16", 0xfd00c0},
+		{"hitcount % 10 == 0", 1},
+		{"1 + 2 * 3 - -1", 8},
+		{"(1 + 2) * 3", 9},
+		{"Y & ~$0f ^ 1", 0xf1},
+		{"X <= 2 && X >= 2 && X < 3 && !(X > 2) && X != 1", 1},
+		{"0 || A", 1},
+	} {
+		cond, err := ParseCondition(c.text)
+		if err != nil {
+			t.Errorf("%q: %v", c.text, err)
+			continue
+		}
+
+		if value, err := cond.Eval(&reg, mem, 20); err != nil || value != c.value {
+			t.Errorf("%q = %#x not %#x, %v", c.text, value, c.value, err)
+		}
+	}
+
+	cond, err := ParseCondition("A / (X - 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cond.Eval(&reg, mem, 20); err == nil {
+		t.Error("No error dividing by zero")
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"A ==",
+		"Q",
+		"[$0300",
+		"(A",
+		"A B",
+		"$zz",
+		"A @ 1",
+	} {
+		if _, err := ParseCondition(text); err == nil {
+			t.Errorf("No error for %q", text)
+		}
+	}
+}
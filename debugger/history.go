@@ -0,0 +1,245 @@
+package debugger
+
+import (
+	"errors"
+
+	"github.com/mpicard/gones"
+)
+
+// ErrNoHistory is returned when stepping back past the oldest recorded
+// instruction
+var ErrNoHistory = errors.New("no more history")
+
+// memoryWrite is a write made by a recorded instruction
+type memoryWrite struct {
+	address  uint16
+	oldValue uint8
+	value    uint8
+}
+
+// record is the state before an instruction was executed and what it wrote
+type record struct {
+	state  cpu.State
+	writes []memoryWrite
+}
+
+// Snapshot is the CPU state and the full contents of memory at a point in
+// the history. Memory is nil when the CPU's memory isn't a DebugMemory, as
+// reading it could have side effects
+type Snapshot struct {
+	State  cpu.State
+	Memory []uint8
+	index  int // of the record that follows the snapshot
+}
+
+// history is a log of the instructions executed, with a snapshot taken
+// every interval instructions. The log always starts at a snapshot so that
+// trimming it leaves a complete point to go back to
+type history struct {
+	limit     int
+	interval  int
+	records   []record
+	snapshots []*Snapshot
+}
+
+// WriteRecord is a write to memory found by LastWrite
+type WriteRecord struct {
+	State    cpu.State // before the instruction that wrote was executed
+	Address  uint16
+	OldValue uint8
+	Value    uint8
+}
+
+// Record starts recording the instructions executed by Step, and so by all
+// the ways of running, so that they can be undone. At least limit
+// instructions are kept and a Snapshot is taken every interval of them.
+// A limit of 0 stops recording and forgets the history. Only writes to
+// memory that is a DebugMemory can be undone
+func (d *Debugger) Record(limit, interval int) {
+	if limit <= 0 {
+		d.history = nil
+		return
+	}
+	if interval <= 0 || interval > limit {
+		interval = limit
+	}
+	d.history = &history{limit: limit, interval: interval}
+}
+
+// History returns the number of instructions that can be stepped back
+func (d *Debugger) History() int {
+	if d.history == nil {
+		return 0
+	}
+	return len(d.history.records)
+}
+
+// Snapshots returns the snapshots in the history, oldest first
+func (d *Debugger) Snapshots() []*Snapshot {
+	if d.history == nil {
+		return nil
+	}
+	return d.history.snapshots
+}
+
+// snapshot peeks at all of memory, if it can be peeked at
+func (d *Debugger) snapshot() *Snapshot {
+	s := &Snapshot{State: d.CPU.SaveState()}
+	mem, ok := d.mem.(cpu.DebugMemory)
+	if !ok {
+		return s
+	}
+
+	s.Memory = make([]uint8, 0x10000)
+	for address := range s.Memory {
+		s.Memory[address] = mem.Peek(uint16(address))
+	}
+	return s
+}
+
+// begin records the state before Step executes an instruction
+func (d *Debugger) begin() {
+	h := d.history
+	if h == nil {
+		return
+	}
+
+	if len(h.records)%h.interval == 0 {
+		if len(h.records) >= h.limit+h.interval {
+			h.records = append([]record(nil), h.records[h.interval:]...)
+			h.snapshots = h.snapshots[1:]
+			for _, s := range h.snapshots {
+				s.index -= h.interval
+			}
+		}
+
+		s := d.snapshot()
+		s.index = len(h.records)
+		h.snapshots = append(h.snapshots, s)
+	}
+
+	h.records = append(h.records, record{state: d.CPU.SaveState()})
+}
+
+// written records a write by the instruction being executed
+func (d *Debugger) written(address uint16, oldValue, value uint8) {
+	if d.history == nil || len(d.history.records) == 0 {
+		return
+	}
+
+	r := &d.history.records[len(d.history.records)-1]
+	r.writes = append(r.writes, memoryWrite{address, oldValue, value})
+}
+
+// undo takes the last instruction off the history
+func (d *Debugger) undo() (r record) {
+	h := d.history
+	r = h.records[len(h.records)-1]
+	h.records = h.records[:len(h.records)-1]
+
+	for i := len(r.writes) - 1; i >= 0; i-- {
+		d.Poke(r.writes[i].address, r.writes[i].oldValue)
+	}
+	d.CPU.RestoreState(r.state)
+
+	if n := len(h.snapshots); n > 0 && h.snapshots[n-1].index == len(h.records) {
+		h.snapshots = h.snapshots[:n-1]
+	}
+	return
+}
+
+// StepBack undoes the last instruction executed
+func (d *Debugger) StepBack() error {
+	if d.History() == 0 {
+		return ErrNoHistory
+	}
+	d.undo()
+	return nil
+}
+
+// ReverseContinue steps back until it reaches a breakpoint, an execute
+// watchpoint or an instruction that wrote to a write watchpoint, returning
+// the same errors as Run. Breakpoint conditions are evaluated but hit counts
+// are left alone. ErrNoHistory is returned at the start of the history
+func (d *Debugger) ReverseContinue() error {
+	for {
+		if d.History() == 0 {
+			return ErrNoHistory
+		}
+
+		r := d.undo()
+		pc := d.CPU.Registers.PC
+		for _, b := range d.Breakpoints() {
+			if b.Address != pc {
+				continue
+			}
+			if stop, err := d.stops(b); err != nil {
+				return err
+			} else if stop {
+				return &BreakpointError{b}
+			}
+		}
+
+		for _, w := range d.Watchpoints() {
+			if w.Access&Execute != 0 && w.contains(pc) {
+				return &WatchpointError{w, pc, d.Peek(pc), Execute}
+			}
+			if w.Access&Write == 0 {
+				continue
+			}
+			for _, write := range r.writes {
+				if w.contains(write.address) {
+					return &WatchpointError{w, write.address, write.value, Write}
+				}
+			}
+		}
+	}
+}
+
+// Restore goes back to a snapshot from Snapshots, forgetting the history
+// after it
+func (d *Debugger) Restore(s *Snapshot) error {
+	h := d.history
+	if h == nil || len(h.snapshots) == 0 {
+		return ErrNoHistory
+	}
+
+	for i, snapshot := range h.snapshots {
+		if snapshot != s {
+			continue
+		}
+
+		if s.Memory == nil {
+			for len(h.records) > s.index {
+				d.undo()
+			}
+			return nil
+		}
+		for address, value := range s.Memory {
+			d.Poke(uint16(address), value)
+		}
+		d.CPU.RestoreState(s.State)
+		h.records = h.records[:s.index]
+		h.snapshots = h.snapshots[:i]
+		return nil
+	}
+	return errors.New("snapshot is not in the history")
+}
+
+// LastWrite returns the most recent recorded write to address
+func (d *Debugger) LastWrite(address uint16) (w WriteRecord, ok bool) {
+	if d.history == nil {
+		return
+	}
+
+	records := d.history.records
+	for i := len(records) - 1; i >= 0; i-- {
+		writes := records[i].writes
+		for j := len(writes) - 1; j >= 0; j-- {
+			if writes[j].address == address {
+				return WriteRecord{records[i].state, address, writes[j].oldValue, writes[j].value}, true
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,243 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/asm"
+)
+
+const counter = `
+	.org $0200
+	LDX #0
+loop:	INX
+	STX $10
+	TXA
+	ASL
+	STA $0300,X
+	CPX #100
+	BNE loop
+done:	JMP done
+`
+
+func setupCounter(t *testing.T) (*Debugger, map[string]uint16) {
+	p, err := asm.Assemble(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cpu.NewCPU(cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE))
+	p.Load(c.Memory)
+	c.Registers.PC = 0x0200
+	return New(c), p.Labels
+}
+
+func TestStepBack(t *testing.T) {
+	d, _ := setupCounter(t)
+	d.Record(1000, 16)
+
+	var registers []cpu.Registers
+	var cycles []uint64
+	var memory [][]uint8
+	for i := 0; i < 50; i++ {
+		registers = append(registers, d.CPU.Registers)
+		cycles = append(cycles, d.CPU.Cycles())
+		memory = append(memory, d.snapshot().Memory)
+		if err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if d.History() != 50 || len(d.Snapshots()) != 4 {
+		t.Errorf("History %d, %d snapshots", d.History(), len(d.Snapshots()))
+	}
+
+	for i := 49; i >= 0; i-- {
+		if err := d.StepBack(); err != nil {
+			t.Fatal(err)
+		}
+		if d.CPU.Registers != registers[i] || d.CPU.Cycles() != cycles[i] {
+			t.Fatalf("Registers after stepping back to %d %+v != %+v", i, d.CPU.Registers, registers[i])
+		}
+		if string(d.snapshot().Memory) != string(memory[i]) {
+			t.Fatalf("Memory after stepping back to %d differs", i)
+		}
+	}
+
+	if err := d.StepBack(); err != ErrNoHistory {
+		t.Errorf("StepBack past the start returned %v", err)
+	}
+}
+
+func TestStepBackReadOnly(t *testing.T) {
+	mem := cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE)
+	mem.Poke(0x0200, 0x8d) // STA $8000
+	mem.Poke(0x0201, 0x00)
+	mem.Poke(0x0202, 0x80)
+	mem.Poke(0x8000, 0x42)
+	mem.DisabledWrites()
+
+	c := cpu.NewCPU(mem)
+	c.Registers.PC = 0x0200
+	d := New(c)
+	d.Record(10, 10)
+
+	if err := d.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if w, ok := d.LastWrite(0x8000); ok {
+		t.Errorf("LastWrite %+v to ROM", w)
+	}
+	if err := d.StepBack(); err != nil {
+		t.Fatal(err)
+	}
+	if value := mem.Peek(0x8000); value != 0x42 {
+		t.Errorf("ROM 0x42 != %#02x after stepping back", value)
+	}
+}
+
+// countingMemory counts the reads of a device without Peek and Poke
+type countingMemory struct {
+	cpu.Memory
+	reads int
+}
+
+func (mem *countingMemory) Read(address uint16) uint8 {
+	mem.reads++
+	return mem.Memory.Read(address)
+}
+
+func TestRecordReads(t *testing.T) {
+	reads := func(attach bool, limit int) int {
+		basic := cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE)
+		basic.Poke(0x0200, 0x8d) // STA $2007
+		basic.Poke(0x0201, 0x07)
+		basic.Poke(0x0202, 0x20)
+		mem := &countingMemory{Memory: basic}
+
+		c := cpu.NewCPU(mem)
+		c.Registers.PC = 0x0200
+		if !attach {
+			c.Execute()
+			return mem.reads
+		}
+
+		d := New(c)
+		d.Record(limit, 1)
+		if err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if limit > 0 && d.Snapshots()[0].Memory != nil {
+			t.Error("Snapshot of memory that can't be peeked at")
+		}
+		return mem.reads
+	}
+
+	expected := reads(false, 0)
+	for _, limit := range []int{0, 10} {
+		if n := reads(true, limit); n != expected {
+			t.Errorf("%d reads with the debugger recording %d, not %d", n, limit, expected)
+		}
+	}
+}
+
+func TestReverseContinue(t *testing.T) {
+	d, labels := setupCounter(t)
+	d.Record(1000, 100)
+
+	if err := d.RunTo(labels["done"]); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := d.BreakIf(labels["loop"], "X == 41")
+	if _, ok := d.ReverseContinue().(*BreakpointError); !ok || d.CPU.Registers.X != 41 {
+		t.Fatalf("Did not stop at X == 41, X %#02x", d.CPU.Registers.X)
+	}
+	if d.Memory().Read(0x0300+42) != 0 || d.Memory().Read(0x0300+41) != 82 {
+		t.Error("Memory was not undone")
+	}
+	d.Delete(b.ID)
+
+	d.Watch(0x0300+10, 0x0300+10, Write)
+	err := d.ReverseContinue()
+	if e, ok := err.(*WatchpointError); !ok || e.Value != 20 || d.CPU.Registers.X != 10 {
+		t.Fatalf("ReverseContinue returned %v with X %#02x", err, d.CPU.Registers.X)
+	}
+
+	if err = d.ReverseContinue(); err != ErrNoHistory || d.CPU.Registers.PC != 0x0200 {
+		t.Errorf("ReverseContinue returned %v at PC %#04x", err, d.CPU.Registers.PC)
+	}
+}
+
+func TestLastWrite(t *testing.T) {
+	d, labels := setupCounter(t)
+	d.Record(1000, 100)
+
+	if _, ok := d.LastWrite(0x0010); ok {
+		t.Error("LastWrite found a write before running")
+	}
+
+	if err := d.RunTo(labels["done"]); err != nil {
+		t.Fatal(err)
+	}
+
+	w, ok := d.LastWrite(0x0010)
+	if !ok || w.Value != 100 || w.OldValue != 99 || w.State.Registers.PC != labels["loop"]+1 {
+		t.Errorf("LastWrite %+v", w)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	d, _ := setupCounter(t)
+	d.Record(20, 10)
+
+	for i := 0; i < 100; i++ {
+		if err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if d.History() < 20 || d.History() > 30 || len(d.Snapshots()) != 3 {
+		t.Fatalf("History %d, %d snapshots", d.History(), len(d.Snapshots()))
+	}
+
+	s := d.Snapshots()[1]
+	if err := d.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if d.CPU.Registers != s.State.Registers || d.History() != 10 || len(d.Snapshots()) != 1 {
+		t.Errorf("Restored to %+v with history %d", d.CPU.Registers, d.History())
+	}
+
+	if err := d.Restore(s); err == nil {
+		t.Error("Restored a snapshot no longer in the history")
+	}
+}
+
+func TestRestoreWithoutMemory(t *testing.T) {
+	p, err := asm.Assemble(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic := cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE)
+	p.Load(basic)
+	c := cpu.NewCPU(&countingMemory{Memory: basic})
+	c.Registers.PC = 0x0200
+	d := New(c)
+	d.Record(20, 10)
+
+	for i := 0; i < 15; i++ {
+		if err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := d.Snapshots()[1]
+	if err := d.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if d.CPU.Registers != s.State.Registers || d.History() != 10 || len(d.Snapshots()) != 1 {
+		t.Errorf("Restored to %+v with history %d", d.CPU.Registers, d.History())
+	}
+}
@@ -0,0 +1,111 @@
+package cpu
+
+import "fmt"
+
+// Disassembly is an instruction decoded from memory
+type Disassembly struct {
+	Address     uint16
+	OpCode      OpCode
+	Instruction *Instruction // nil if the table has no instruction for OpCode
+	Operands    []uint8      // the bytes following the opcode
+	Target      uint16       // the address the operand refers to, see HasTarget
+	HasTarget   bool         // false for implied, accumulator and immediate
+	Text        string       // e.g. "LDA $0200,X"
+}
+
+// Size returns the number of bytes of the instruction including the opcode
+func (d Disassembly) Size() int {
+	return len(d.Operands) + 1
+}
+
+// Bytes returns the opcode and operands
+func (d Disassembly) Bytes() []uint8 {
+	return append([]uint8{uint8(d.OpCode)}, d.Operands...)
+}
+
+func (d Disassembly) String() string {
+	return d.Text
+}
+
+// Disassemble decodes the instruction at addr in mem. The Target of indexed
+// and indirect modes is the base address, before it is indexed or read
+// through, and that of relative branches their destination. An opcode
+// missing from the table disassembles as a single .byte. Memory is read with
+// Peek
+func (instructions InstructionTable) Disassemble(mem Memory, addr uint16) (d Disassembly) {
+	d.Address = addr
+	d.OpCode = OpCode(Peek(mem, addr))
+	d.Instruction = instructions.opcodes[d.OpCode]
+	if d.Instruction == nil {
+		d.Text = fmt.Sprintf(".byte $%02X", uint8(d.OpCode))
+		return
+	}
+
+	mode := d.Instruction.Mode
+	for i := 1; i <= mode.Operands(); i++ {
+		d.Operands = append(d.Operands, Peek(mem, addr+uint16(i)))
+	}
+
+	var operand uint16
+	switch len(d.Operands) {
+	case 1:
+		operand = uint16(d.Operands[0])
+	case 2:
+		operand = (uint16(d.Operands[1]) << 8) | uint16(d.Operands[0])
+	}
+
+	d.HasTarget = true
+	d.Target = operand
+
+	var text string
+	switch mode {
+	case ModeImplied:
+		d.HasTarget = false
+	case ModeAccumulator:
+		text = "A"
+		d.HasTarget = false
+	case ModeImmediate:
+		text = fmt.Sprintf("#$%02X", operand)
+		d.HasTarget = false
+	case ModeZeroPage:
+		text = fmt.Sprintf("$%02X", operand)
+	case ModeZeroPageX:
+		text = fmt.Sprintf("$%02X,X", operand)
+	case ModeZeroPageY:
+		text = fmt.Sprintf("$%02X,Y", operand)
+	case ModeAbsolute:
+		text = fmt.Sprintf("$%04X", operand)
+	case ModeAbsoluteX:
+		text = fmt.Sprintf("$%04X,X", operand)
+	case ModeAbsoluteY:
+		text = fmt.Sprintf("$%04X,Y", operand)
+	case ModeIndirect:
+		text = fmt.Sprintf("($%04X)", operand)
+	case ModeIndexedIndirect:
+		text = fmt.Sprintf("($%02X,X)", operand)
+	case ModeIndirectIndexed:
+		text = fmt.Sprintf("($%02X),Y", operand)
+	case ModeZeroPageIndirect:
+		text = fmt.Sprintf("($%02X)", operand)
+	case ModeAbsoluteIndexedIndirect:
+		text = fmt.Sprintf("($%04X,X)", operand)
+	case ModeRelative:
+		d.Target = addr + 2 + uint16(int8(operand))
+		text = fmt.Sprintf("$%04X", d.Target)
+	}
+
+	if !d.HasTarget {
+		d.Target = 0
+	}
+
+	d.Text = d.Instruction.Mneumonic
+	if text != "" {
+		d.Text += " " + text
+	}
+	return
+}
+
+// Disassemble decodes the instruction at addr in the CPU's memory
+func (cpu *CPU) Disassemble(addr uint16) Disassembly {
+	return cpu.Instructions.Disassemble(cpu.Memory, addr)
+}
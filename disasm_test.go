@@ -0,0 +1,84 @@
+package cpu
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	instructions := NewInstructionTable()
+	instructions.InitInstructions()
+
+	for _, d := range []struct {
+		bytes     []uint8
+		text      string
+		target    uint16
+		hasTarget bool
+	}{
+		{[]uint8{0xea}, "NOP", 0, false},
+		{[]uint8{0x0a}, "ASL A", 0, false},
+		{[]uint8{0xa9, 0xff}, "LDA #$FF", 0, false},
+		{[]uint8{0xa5, 0x12}, "LDA $12", 0x0012, true},
+		{[]uint8{0xb5, 0x12}, "LDA $12,X", 0x0012, true},
+		{[]uint8{0xb6, 0x12}, "LDX $12,Y", 0x0012, true},
+		{[]uint8{0xad, 0x34, 0x12}, "LDA $1234", 0x1234, true},
+		{[]uint8{0xbd, 0x34, 0x12}, "LDA $1234,X", 0x1234, true},
+		{[]uint8{0xbe, 0x34, 0x12}, "LDX $1234,Y", 0x1234, true},
+		{[]uint8{0x6c, 0x34, 0x12}, "JMP ($1234)", 0x1234, true},
+		{[]uint8{0xa1, 0x12}, "LDA ($12,X)", 0x0012, true},
+		{[]uint8{0xb1, 0x12}, "LDA ($12),Y", 0x0012, true},
+		{[]uint8{0xd0, 0xfe}, "BNE $0200", 0x0200, true},
+		{[]uint8{0x10, 0x10}, "BPL $0212", 0x0212, true},
+		{[]uint8{0x20, 0x00, 0xc0}, "JSR $C000", 0xc000, true},
+		{[]uint8{0x02}, "JAM", 0, false},
+		{[]uint8{0x03}, ".byte $03", 0, false},
+	} {
+		for i, value := range d.bytes {
+			mem.Write(0x0200+uint16(i), value)
+		}
+
+		result := instructions.Disassemble(mem, 0x0200)
+
+		if result.Text != d.text {
+			t.Errorf("%#02x disassembled to %q not %q", d.bytes[0], result.Text, d.text)
+		}
+
+		if result.Size() != len(d.bytes) {
+			t.Errorf("%v has size %v not %v", d.text, result.Size(), len(d.bytes))
+		}
+
+		if result.HasTarget != d.hasTarget || result.Target != d.target {
+			t.Errorf("%v has target %#04x not %#04x", d.text, result.Target, d.target)
+		}
+	}
+}
+
+func TestInstructionSizes(t *testing.T) {
+	for _, variant := range []Variant{NMOS6502, CMOS65C02} {
+		cpu := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithVariant(variant), WithIllegalOpcodes())
+
+		// every instruction but the control flow ones leaves PC after its
+		// operands
+		for o := 0; o < 0x100; o++ {
+			inst := cpu.Instructions.Lookup(OpCode(o))
+			if inst == nil || inst.Mneumonic == "JAM" {
+				continue
+			}
+			switch inst.Mode {
+			case ModeRelative, ModeIndirect, ModeAbsoluteIndexedIndirect:
+				continue
+			}
+			switch inst.Mneumonic {
+			case "JMP", "JSR", "RTS", "RTI", "BRK":
+				continue
+			}
+
+			cpu.Registers.PC = 0x0200
+			cpu.Memory.Write(0x0200, uint8(o))
+			cpu.Execute()
+
+			if cpu.Registers.PC != 0x0200+uint16(inst.Size) {
+				t.Errorf("%v %v %#02x has size %v but moved PC to %#04x",
+					variant, inst.Mneumonic, o, inst.Size, cpu.Registers.PC)
+			}
+		}
+	}
+}
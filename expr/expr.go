@@ -0,0 +1,328 @@
+// Package expr parses and evaluates the integer expressions of the
+// assembler's operands and the debugger's breakpoint conditions
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Env is what an Expr is evaluated against
+type Env interface {
+	// Symbol returns the value of name, or an UndefinedError
+	Symbol(name string) (int, error)
+	// PC returns the value of *
+	PC() int
+	// Read returns the byte at address for [address]
+	Read(address uint16) (uint8, error)
+}
+
+// UndefinedError is returned for a symbol that has no value
+type UndefinedError string
+
+func (u UndefinedError) Error() string {
+	return fmt.Sprintf("undefined symbol %v", string(u))
+}
+
+// Expr is a parsed expression, e.g.
+//
+//	<(table + 2 * X)
+//	A == $40 && [$0300] > 3
+//
+// Numbers are decimal, $hex, %binary or 'c' characters, * is the PC,
+// [addr] reads memory and names are symbols looked up when the expression
+// is evaluated. The operators are, from lowest to highest precedence, ||,
+// &&, |, ^, &, == and !=, < <= > and >=, << and >>, + and -, * / and %,
+// and the unary -, ~, !, < (low byte) and > (high byte). Comparisons and
+// ! are 0 or 1
+type Expr struct {
+	text    string
+	eval    evaluator
+	symbols []string
+}
+
+type evaluator func(env Env) (int, error)
+
+// Parse parses text
+func Parse(text string) (*Expr, error) {
+	p := &parser{text: text}
+	eval, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(text) {
+		return nil, fmt.Errorf("unexpected %q in expression %q", text[p.pos:], text)
+	}
+	return &Expr{text, eval, p.symbols}, nil
+}
+
+// Eval evaluates the expression against env
+func (e *Expr) Eval(env Env) (int, error) {
+	return e.eval(env)
+}
+
+// Symbols returns the names the expression refers to
+func (e *Expr) Symbols() []string {
+	return e.symbols
+}
+
+func (e *Expr) String() string {
+	return e.text
+}
+
+// operators are listed longest first so that "<<" is not read as "<"
+var operators = []string{
+	"||", "&&", "==", "!=", "<=", ">=", "<<", ">>",
+	"|", "^", "&", "<", ">", "+", "-", "*", "/", "%",
+}
+
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+var arithmetic = map[string]func(left, right int) int{
+	"|":  func(left, right int) int { return left | right },
+	"^":  func(left, right int) int { return left ^ right },
+	"&":  func(left, right int) int { return left & right },
+	"==": func(left, right int) int { return boolean(left == right) },
+	"!=": func(left, right int) int { return boolean(left != right) },
+	"<":  func(left, right int) int { return boolean(left < right) },
+	"<=": func(left, right int) int { return boolean(left <= right) },
+	">":  func(left, right int) int { return boolean(left > right) },
+	">=": func(left, right int) int { return boolean(left >= right) },
+	"<<": func(left, right int) int { return left << uint(right) },
+	">>": func(left, right int) int { return left >> uint(right) },
+	"+":  func(left, right int) int { return left + right },
+	"-":  func(left, right int) int { return left - right },
+	"*":  func(left, right int) int { return left * right },
+	"/":  func(left, right int) int { return left / right },
+	"%":  func(left, right int) int { return left % right },
+}
+
+var unaryOperators = map[byte]func(value int) int{
+	'-': func(value int) int { return -value },
+	'~': func(value int) int { return ^value },
+	'!': func(value int) int { return boolean(value == 0) },
+	'<': func(value int) int { return value & 0xff },
+	'>': func(value int) int { return (value >> 8) & 0xff },
+}
+
+func boolean(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type parser struct {
+	text    string
+	pos     int
+	symbols []string
+}
+
+func (p *parser) binary(level int) (evaluator, error) {
+	if level == len(binaryOperators) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		operator := ""
+		for _, op := range operators {
+			if strings.HasPrefix(p.text[p.pos:], op) {
+				operator = op
+				break
+			}
+		}
+
+		found := false
+		for _, op := range binaryOperators[level] {
+			found = found || op == operator
+		}
+		if !found {
+			return left, nil
+		}
+		p.pos += len(operator)
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = p.combine(operator, left, right)
+	}
+}
+
+func (p *parser) combine(operator string, left, right evaluator) evaluator {
+	// || and && only evaluate the right side when they need it
+	if operator == "||" || operator == "&&" {
+		return func(env Env) (int, error) {
+			value, err := left(env)
+			if err != nil || (value != 0) == (operator == "||") {
+				return boolean(value != 0), err
+			}
+			value, err = right(env)
+			return boolean(value != 0), err
+		}
+	}
+
+	text := p.text
+	op := arithmetic[operator]
+	return func(env Env) (int, error) {
+		l, err := left(env)
+		if err != nil {
+			return 0, err
+		}
+		r, err := right(env)
+		if err != nil {
+			return 0, err
+		}
+		if r == 0 && (operator == "/" || operator == "%") {
+			return 0, fmt.Errorf("division by zero in expression %q", text)
+		}
+		return op(l, r), nil
+	}
+}
+
+func (p *parser) unary() (evaluator, error) {
+	p.skipSpace()
+	if p.pos == len(p.text) {
+		return nil, fmt.Errorf("missing operand in expression %q", p.text)
+	}
+
+	op, ok := unaryOperators[p.text[p.pos]]
+	if !ok {
+		return p.primary()
+	}
+	p.pos++
+
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(env Env) (int, error) {
+		value, err := operand(env)
+		return op(value), err
+	}, nil
+}
+
+func (p *parser) primary() (evaluator, error) {
+	c := p.text[p.pos]
+	switch {
+	case c == '(' || c == '[':
+		closing := byte(')')
+		if c == '[' {
+			closing = ']'
+		}
+		p.pos++
+
+		inner, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos == len(p.text) || p.text[p.pos] != closing {
+			return nil, fmt.Errorf("missing %c in expression %q", closing, p.text)
+		}
+		p.pos++
+
+		if c == '(' {
+			return inner, nil
+		}
+		return func(env Env) (int, error) {
+			address, err := inner(env)
+			if err != nil {
+				return 0, err
+			}
+			value, err := env.Read(uint16(address))
+			return int(value), err
+		}, nil
+
+	case c == '*':
+		p.pos++
+		return func(env Env) (int, error) {
+			return env.PC(), nil
+		}, nil
+
+	case c == '\'':
+		if p.pos+2 >= len(p.text) || p.text[p.pos+2] != '\'' {
+			return nil, fmt.Errorf("bad character in expression %q", p.text)
+		}
+		value := int(p.text[p.pos+1])
+		p.pos += 3
+		return constant(value), nil
+
+	case c == '$':
+		return p.number(1, 16)
+
+	case c == '%':
+		return p.number(1, 2)
+
+	case c >= '0' && c <= '9':
+		return p.number(0, 10)
+
+	case isNameStart(c):
+		start := p.pos
+		for p.pos < len(p.text) && isNamePart(p.text[p.pos]) {
+			p.pos++
+		}
+		name := p.text[start:p.pos]
+		p.symbols = append(p.symbols, name)
+		return func(env Env) (int, error) {
+			return env.Symbol(name)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q in expression %q", p.text[p.pos:], p.text)
+}
+
+func (p *parser) number(prefix int, base int) (evaluator, error) {
+	p.pos += prefix
+	start := p.pos
+	for p.pos < len(p.text) && isNamePart(p.text[p.pos]) && p.text[p.pos] != '.' {
+		p.pos++
+	}
+
+	n, err := strconv.ParseInt(p.text[start:p.pos], base, 32)
+	if err != nil {
+		return nil, fmt.Errorf("bad number %q in expression %q", p.text[start-prefix:p.pos], p.text)
+	}
+	return constant(int(n)), nil
+}
+
+func constant(value int) evaluator {
+	return func(Env) (int, error) {
+		return value, nil
+	}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// names start with a letter, _ or @ for the assembler's local labels, and
+// may contain . for the debugger's P.C
+func isNameStart(c byte) bool {
+	return c == '_' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
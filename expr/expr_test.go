@@ -0,0 +1,101 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+type env map[string]int
+
+func (e env) Symbol(name string) (int, error) {
+	if value, ok := e[name]; ok {
+		return value, nil
+	}
+	return 0, UndefinedError(name)
+}
+
+func (e env) PC() int {
+	return 0xc000
+}
+
+func (e env) Read(address uint16) (uint8, error) {
+	if address == 0xffff {
+		return 0, errors.New("no memory")
+	}
+	return uint8(address + 1), nil
+}
+
+func TestEval(t *testing.T) {
+	symbols := env{"table": 0x1234, "@loop": 7, "P.C": 1}
+
+	for _, c := range []struct {
+		text  string
+		value int
+	}{
+		{"42", 42},
+		{"$ff + %101 + 'A'", 0xff + 5 + 0x41},
+		{"* + 2", 0xc002},
+		{"2 * 3 % 4", 2},
+		{">table | <table << 8", 0x3412},
+		{"table >> 4 & $f", 0x3},
+		{"-1 + ~0 + !0 + !5", -1},
+		{"1 + 2 * (3 - 1)", 5},
+		{"[$0300 + 2]", 0x03},
+		{"@loop == 7 && P.C", 1},
+		{"1 < 2 && 2 <= 2 && 3 > 2 && 3 >= 4", 0},
+		{"0 || table != 0", 1},
+		{"0 && undefined", 0},
+		{"1 || undefined", 1},
+		{"5 ^ 3", 6},
+	} {
+		e, err := Parse(c.text)
+		if err != nil {
+			t.Errorf("%q: %v", c.text, err)
+			continue
+		}
+
+		if value, err := e.Eval(symbols); err != nil || value != c.value {
+			t.Errorf("%q = %#x not %#x, %v", c.text, value, c.value, err)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e, err := Parse("table + later")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.Eval(env{"table": 1}); err != UndefinedError("later") {
+		t.Errorf("Eval returned %v", err)
+	}
+	if len(e.Symbols()) != 2 || e.Symbols()[1] != "later" {
+		t.Errorf("Symbols %v", e.Symbols())
+	}
+
+	for _, text := range []string{"1 / 0", "1 % (2 - 2)", "[$ffff]"} {
+		if e, err = Parse(text); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = e.Eval(env{}); err == nil {
+			t.Errorf("No error evaluating %q", text)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"1 +",
+		"(1",
+		"[1",
+		"1 2",
+		"$zz",
+		"%2",
+		"'ab",
+		"1 # 2",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("No error for %q", text)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+// Package gdbstub serves a CPU over the GDB Remote Serial Protocol, so that
+// GDB and other debuggers that speak it can attach over TCP.
+//
+// GDB has no 6502 target, the registers are sent in the order A, X, Y, P and
+// SP, a byte each, followed by PC as two bytes, low byte first
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/debugger"
+)
+
+// Signals reported to GDB when the CPU stops
+const (
+	sigint  = 2
+	sigill  = 4
+	sigtrap = 5
+)
+
+// Server answers GDB packets by controlling a Debugger
+type Server struct {
+	debugger *debugger.Debugger
+	points   map[string]int // debugger IDs by Z packet type, address and kind
+}
+
+// New creates a Server for d
+func New(d *debugger.Debugger) *Server {
+	return &Server{debugger: d, points: make(map[string]int)}
+}
+
+// ListenAndServe listens on the TCP address addr, e.g. "localhost:2345",
+// and calls Serve
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves them one at a time
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		err = s.ServeConn(conn)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// conn is a connection to GDB. Acknowledgements are written by the reading
+// goroutine and packets by Serve, so writes are locked
+type conn struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+func (c *conn) write(data string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, err := io.WriteString(c.w, data)
+	return err
+}
+
+func (c *conn) send(packet string) error {
+	return c.write(fmt.Sprintf("$%s#%02x", packet, checksum(packet)))
+}
+
+func checksum(packet string) (sum uint8) {
+	for i := 0; i < len(packet); i++ {
+		sum += packet[i]
+	}
+	return
+}
+
+// read sends the packets received on r to packets until it fails or quit is
+// closed. A 0x03 byte, sent by GDB for Ctrl-C, interrupts the debugger
+func (s *Server) read(r io.Reader, c *conn, packets chan<- string, errs chan<- error, quit <-chan struct{}) {
+	defer close(packets)
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		switch b {
+		case 0x03:
+			s.debugger.Interrupt()
+			continue
+		case '$':
+		default:
+			continue
+		}
+
+		packet, err := br.ReadString('#')
+		if err != nil {
+			errs <- err
+			return
+		}
+		packet = packet[:len(packet)-1]
+
+		var sum [2]byte
+		if _, err = io.ReadFull(br, sum[:]); err != nil {
+			errs <- err
+			return
+		}
+
+		if n, err := strconv.ParseUint(string(sum[:]), 16, 8); err != nil || uint8(n) != checksum(packet) {
+			err = c.write("-")
+			if err != nil {
+				errs <- err
+				return
+			}
+			continue
+		}
+
+		if err = c.write("+"); err != nil {
+			errs <- err
+			return
+		}
+		select {
+		case packets <- packet:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// ServeConn serves a single GDB session on rw until GDB kills or detaches
+// from the target, or the connection is closed
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	c := &conn{w: rw}
+	packets := make(chan string)
+	errs := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+	go s.read(rw, c, packets, errs, quit)
+
+	for packet := range packets {
+		reply, done := s.handle(packet)
+		if err := c.send(reply); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+
+	if err := <-errs; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// handle returns the reply to packet, and true if the session is over
+func (s *Server) handle(packet string) (reply string, done bool) {
+	if packet == "" {
+		return "", false
+	}
+
+	d := s.debugger
+	args := packet[1:]
+	switch packet[0] {
+	case '?':
+		return fmt.Sprintf("S%02x", sigtrap), false
+
+	case 'g':
+		return hex.EncodeToString(registers(&d.CPU.Registers)), false
+
+	case 'G':
+		b, err := hex.DecodeString(args)
+		if err != nil || len(b) != 7 {
+			return "E01", false
+		}
+		setRegisters(&d.CPU.Registers, b)
+		return "OK", false
+
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 8)
+		if err != nil || n > 5 {
+			return "E01", false
+		}
+		b := registers(&d.CPU.Registers)
+		if n == 5 {
+			return hex.EncodeToString(b[5:]), false
+		}
+		return hex.EncodeToString(b[n : n+1]), false
+
+	case 'P':
+		parts := strings.SplitN(args, "=", 2)
+		if len(parts) != 2 {
+			return "E01", false
+		}
+		n, err := strconv.ParseUint(parts[0], 16, 8)
+		value, err2 := hex.DecodeString(parts[1])
+		if err != nil || err2 != nil || n > 5 || (n < 5 && len(value) != 1) || (n == 5 && len(value) != 2) {
+			return "E01", false
+		}
+		b := registers(&d.CPU.Registers)
+		copy(b[n:], value)
+		setRegisters(&d.CPU.Registers, b)
+		return "OK", false
+
+	case 'm':
+		address, length, err := addressLength(args)
+		if err != nil {
+			return "E01", false
+		}
+		b := make([]uint8, length)
+		for i := range b {
+			b[i] = d.Peek(address + uint16(i))
+		}
+		return hex.EncodeToString(b), false
+
+	case 'M':
+		parts := strings.SplitN(args, ":", 2)
+		if len(parts) != 2 {
+			return "E01", false
+		}
+		address, length, err := addressLength(parts[0])
+		b, err2 := hex.DecodeString(parts[1])
+		if err != nil || err2 != nil || len(b) != length {
+			return "E01", false
+		}
+		for i, value := range b {
+			d.Poke(address+uint16(i), value)
+		}
+		return "OK", false
+
+	case 's', 'c':
+		if args != "" {
+			pc, err := strconv.ParseUint(args, 16, 16)
+			if err != nil {
+				return "E01", false
+			}
+			d.CPU.Registers.PC = uint16(pc)
+		}
+		if packet[0] == 's' {
+			return stopReply(d.Step()), false
+		}
+		return stopReply(d.Run()), false
+
+	case 'Z', 'z':
+		return s.point(packet[0] == 'Z', args), false
+
+	case 'k', 'D':
+		return "OK", true
+
+	case 'H':
+		return "OK", false
+
+	case 'q':
+		switch {
+		case strings.HasPrefix(args, "Supported"):
+			return "PacketSize=1000", false
+		case args == "Attached":
+			return "1", false
+		case args == "C":
+			return "QC1", false
+		}
+	}
+
+	return "", false
+}
+
+// point adds or removes a breakpoint or watchpoint for a Z or z packet
+// "type,address,kind". Types 0 and 1 are breakpoints, 2, 3 and 4 write, read
+// and access watchpoints of kind bytes
+func (s *Server) point(insert bool, args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) != 3 {
+		return "E01"
+	}
+	address, length, err := addressLength(parts[1] + "," + parts[2])
+	if err != nil {
+		return "E01"
+	}
+
+	var access debugger.Access
+	switch parts[0] {
+	case "0", "1":
+	case "2":
+		access = debugger.Write
+	case "3":
+		access = debugger.Read
+	case "4":
+		access = debugger.Read | debugger.Write
+	default:
+		return ""
+	}
+
+	id, ok := s.points[args]
+	if !insert {
+		if ok {
+			s.debugger.Delete(id)
+			delete(s.points, args)
+		}
+		return "OK"
+	}
+	if ok {
+		return "OK"
+	}
+
+	if access == 0 {
+		s.points[args] = s.debugger.Break(address).ID
+	} else {
+		if length == 0 {
+			length = 1
+		}
+		s.points[args] = s.debugger.Watch(address, address+uint16(length-1), access).ID
+	}
+	return "OK"
+}
+
+// stopReply reports why running stopped
+func stopReply(err error) string {
+	switch e := err.(type) {
+	case nil, *debugger.BreakpointError:
+		return fmt.Sprintf("S%02x", sigtrap)
+	case *debugger.WatchpointError:
+		kind := map[debugger.Access]string{
+			debugger.Write: "watch",
+			debugger.Read:  "rwatch",
+		}[e.Watchpoint.Access]
+		if e.Watchpoint.Access == debugger.Read|debugger.Write {
+			kind = "awatch"
+		}
+		if kind == "" {
+			return fmt.Sprintf("S%02x", sigtrap)
+		}
+		return fmt.Sprintf("T%02x%s:%04x;", sigtrap, kind, e.Address)
+	case cpu.CPUJammedError, cpu.BadOpCodeError:
+		return fmt.Sprintf("S%02x", sigill)
+	}
+
+	if err == debugger.ErrInterrupted {
+		return fmt.Sprintf("S%02x", sigint)
+	}
+	return fmt.Sprintf("S%02x", sigtrap)
+}
+
+// addressLength parses "address,length"
+func addressLength(args string) (address uint16, length int, err error) {
+	parts := strings.Split(args, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("bad address and length %q", args)
+	}
+
+	a, err := strconv.ParseUint(parts[0], 16, 16)
+	if err != nil {
+		return
+	}
+	l, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return
+	}
+	return uint16(a), int(l), nil
+}
+
+func registers(reg *cpu.Registers) []uint8 {
+	return []uint8{reg.A, reg.X, reg.Y, uint8(reg.P), reg.SP, uint8(reg.PC), uint8(reg.PC >> 8)}
+}
+
+func setRegisters(reg *cpu.Registers, b []uint8) {
+	reg.A = b[0]
+	reg.X = b[1]
+	reg.Y = b[2]
+	reg.P = cpu.Status(b[3])
+	reg.SP = b[4]
+	reg.PC = uint16(b[5]) | uint16(b[6])<<8
+}
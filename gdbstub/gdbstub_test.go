@@ -0,0 +1,182 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mpicard/gones"
+	"github.com/mpicard/gones/asm"
+	"github.com/mpicard/gones/debugger"
+)
+
+// client is a minimal GDB client
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(packet string) {
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", packet, checksum(packet)); err != nil {
+		c.t.Fatal(err)
+	}
+	if b, err := c.r.ReadByte(); err != nil || b != '+' {
+		c.t.Fatalf("%q was not acknowledged, got %q %v", packet, b, err)
+	}
+}
+
+func (c *client) receive() string {
+	if _, err := c.r.ReadString('$'); err != nil {
+		c.t.Fatal(err)
+	}
+	packet, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	packet = packet[:len(packet)-1]
+
+	var sum string
+	for i := 0; i < 2; i++ {
+		b, _ := c.r.ReadByte()
+		sum += string(b)
+	}
+	if sum != fmt.Sprintf("%02x", checksum(packet)) {
+		c.t.Fatalf("Bad checksum %v for %q", sum, packet)
+	}
+	fmt.Fprint(c.conn, "+")
+	return packet
+}
+
+func (c *client) expect(packet, reply string) {
+	c.send(packet)
+	if result := c.receive(); result != reply {
+		c.t.Errorf("%q returned %q not %q", packet, result, reply)
+	}
+}
+
+func setup(t *testing.T) (*client, *debugger.Debugger, map[string]uint16, chan error) {
+	p, err := asm.Assemble(`
+	.org $0200
+start:	LDX #0
+loop:	INX
+	STX $10
+	CPX #3
+	BNE loop
+forever:	JMP forever
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cpu.NewCPU(cpu.NewBasicMemory(cpu.DEFAULT_MEMORY_SIZE))
+	p.Load(c.Memory)
+	c.Registers = cpu.Registers{P: cpu.I | cpu.U, SP: 0xfd, PC: 0x0200}
+	d := debugger.New(c)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("Cannot listen on a local port:", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		done <- New(d).ServeConn(conn)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{t, conn, bufio.NewReader(conn)}, d, p.Labels, done
+}
+
+func TestRegistersAndMemory(t *testing.T) {
+	c, d, _, done := setup(t)
+	defer c.conn.Close()
+
+	c.expect("qSupported:swbreak+", "PacketSize=1000")
+	c.expect("?", "S05")
+	c.expect("g", "00000024fd0002")
+	c.expect("G0102032400ff10", "OK")
+	if r := d.CPU.Registers; r.A != 1 || r.X != 2 || r.Y != 3 || r.SP != 0 || r.PC != 0x10ff {
+		t.Errorf("Registers %+v", r)
+	}
+	c.expect("p5", "ff10")
+	c.expect("P0=42", "OK")
+	c.expect("P5=0002", "OK")
+	c.expect("p0", "42")
+	c.expect("p6", "E01")
+
+	c.expect("m0200,3", "a200e8")
+	c.expect("M0300,2:beef", "OK")
+	c.expect("m02ff,3", "00beef")
+	c.expect("M0300,2:be", "E01")
+	c.expect("vMustReplyEmpty", "")
+
+	c.expect("k", "OK")
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBreakpointsAndStepping(t *testing.T) {
+	c, d, labels, done := setup(t)
+	defer c.conn.Close()
+
+	c.expect("s", "S05")
+	c.expect("p5", fmt.Sprintf("%02x%02x", labels["loop"]&0xff, labels["loop"]>>8))
+
+	c.expect(fmt.Sprintf("Z0,%x,1", labels["loop"]), "OK")
+	c.expect("c", "S05")
+	if d.CPU.Registers.PC != labels["loop"] || d.CPU.Registers.X != 1 {
+		t.Errorf("Stopped at %#04x with X %#02x", d.CPU.Registers.PC, d.CPU.Registers.X)
+	}
+	c.expect(fmt.Sprintf("z0,%x,1", labels["loop"]), "OK")
+
+	c.expect("Z2,10,1", "OK")
+	c.expect("c", "T05watch:0010;")
+	if d.Memory().Read(0x0010) != 2 {
+		t.Errorf("Watchpoint stopped with $10 = %#02x", d.Memory().Read(0x0010))
+	}
+	c.expect("z2,10,1", "OK")
+
+	c.send("c")
+	time.Sleep(10 * time.Millisecond)
+	fmt.Fprint(c.conn, "\x03")
+	if reply := c.receive(); reply != "S02" {
+		t.Errorf("Interrupt returned %q", reply)
+	}
+	if d.CPU.Registers.PC != labels["forever"] {
+		t.Errorf("Interrupted at %#04x", d.CPU.Registers.PC)
+	}
+
+	c.expect("D", "OK")
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBadChecksum(t *testing.T) {
+	c, _, _, done := setup(t)
+	defer c.conn.Close()
+
+	fmt.Fprint(c.conn, "$g#00")
+	if b, _ := c.r.ReadByte(); b != '-' {
+		t.Errorf("Bad checksum acknowledged with %q", b)
+	}
+
+	c.conn.Close()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,168 @@
+package cpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// harteState is a CPU and RAM state in the SingleStepTests/ProcessorTests
+// format
+type harteState struct {
+	PC  uint16      `json:"pc"`
+	S   uint8       `json:"s"`
+	A   uint8       `json:"a"`
+	X   uint8       `json:"x"`
+	Y   uint8       `json:"y"`
+	P   uint8       `json:"p"`
+	RAM [][2]uint16 `json:"ram"`
+}
+
+// harteCycle is one bus access, encoded as [address, value, "read"|"write"]
+type harteCycle access
+
+func (cycle *harteCycle) UnmarshalJSON(data []byte) (err error) {
+	var fields [3]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+
+	address, ok1 := fields[0].(float64)
+	value, ok2 := fields[1].(float64)
+	kind, ok3 := fields[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return fmt.Errorf("bad cycle %s", data)
+	}
+
+	*cycle = harteCycle{uint16(address), uint8(value), kind == "write"}
+	return
+}
+
+type harteTest struct {
+	Name    string       `json:"name"`
+	Initial harteState   `json:"initial"`
+	Final   harteState   `json:"final"`
+	Cycles  []harteCycle `json:"cycles"`
+}
+
+// TestHarteSample runs the vectors vendored in testdata/harte/6502
+func TestHarteSample(t *testing.T) {
+	runHarteTests(t, filepath.Join("testdata", "harte", "6502"), NMOS6502)
+}
+
+// TestHarte runs the full suite from the directory in PROCESSOR_TESTS, such
+// as ProcessorTests/6502/v1. A directory named nes6502 is run on the 2A03
+func TestHarte(t *testing.T) {
+	dir := os.Getenv("PROCESSOR_TESTS")
+	if dir == "" {
+		t.Skip("PROCESSOR_TESTS is not set")
+	}
+
+	variant := NMOS6502
+	if strings.Contains(dir, "nes6502") {
+		variant = Ricoh2A03
+	}
+	runHarteTests(t, dir, variant)
+}
+
+func runHarteTests(t *testing.T, dir string, variant Variant) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skipf("no tests in %v", dir)
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tests []harteTest
+			if err = json.Unmarshal(data, &tests); err != nil {
+				t.Fatal(err)
+			}
+
+			// stop at the first failing vector of each opcode
+			for _, test := range tests {
+				if !runHarteTest(t, test, variant) {
+					break
+				}
+			}
+		})
+	}
+}
+
+func runHarteTest(t *testing.T, test harteTest, variant Variant) bool {
+	mem := newRecordingMemory()
+	cpu := NewCPU(mem, WithVariant(variant), WithIllegalOpcodes(), WithCycleAccuracy(nil))
+
+	cpu.Registers = Registers{
+		A:  test.Initial.A,
+		X:  test.Initial.X,
+		Y:  test.Initial.Y,
+		P:  Status(test.Initial.P),
+		SP: test.Initial.S,
+		PC: test.Initial.PC,
+	}
+	for _, ram := range test.Initial.RAM {
+		mem.Write(ram[0], uint8(ram[1]))
+	}
+	mem.log = nil
+
+	// the JAM opcodes are not emulated past the point where they halt
+	if inst := cpu.Instructions.opcodes[mem.BasicMemory.Read(cpu.Registers.PC)]; inst == nil || inst.Mneumonic == "JAM" {
+		return true
+	}
+
+	if _, err := cpu.Execute(); err != nil {
+		t.Errorf("%v: %v", test.Name, err)
+		return false
+	}
+
+	ok := true
+
+	// B and U do not exist in P, they only show up when it is pushed
+	expected := Registers{
+		A:  test.Final.A,
+		X:  test.Final.X,
+		Y:  test.Final.Y,
+		P:  Status(test.Final.P) & ^(B | U),
+		SP: test.Final.S,
+		PC: test.Final.PC,
+	}
+	registers := cpu.Registers
+	registers.P &= ^(B | U)
+	if registers != expected {
+		t.Errorf("%v: registers %+v != %+v", test.Name, registers, expected)
+		ok = false
+	}
+
+	for _, ram := range test.Final.RAM {
+		if value := mem.BasicMemory.Read(ram[0]); value != uint8(ram[1]) {
+			t.Errorf("%v: memory at %#04x %#02x != %#02x", test.Name, ram[0], value, ram[1])
+			ok = false
+		}
+	}
+
+	if len(mem.log) != len(test.Cycles) {
+		t.Errorf("%v: %v bus accesses != %v", test.Name, len(mem.log), len(test.Cycles))
+		return false
+	}
+	for i, cycle := range test.Cycles {
+		if mem.log[i] != access(cycle) {
+			t.Errorf("%v: cycle %v %+v != %+v", test.Name, i+1, mem.log[i], access(cycle))
+			ok = false
+		}
+	}
+
+	return ok
+}
@@ -13,6 +13,9 @@ type Instruction struct {
 	Mneumonic string
 	OpCode    OpCode
 	Exec      func(*CPU) (status InstructionStatus)
+	Illegal   bool           // unofficial NMOS opcode
+	Mode      AddressingMode // set by AddInstruction
+	Size      uint8          // in bytes including the opcode, set by AddInstruction
 }
 
 // InstructionTable maps OpCodes to an instruction
@@ -20,11 +23,52 @@ type InstructionTable struct {
 	opcodes         []*Instruction
 	cycles          []uint16
 	cyclesPageCross []uint16
+	modes           []AddressingMode
+}
+
+// AddressingMode is how an instruction finds its operand
+type AddressingMode uint8
+
+const (
+	ModeImplied AddressingMode = iota
+	ModeAccumulator
+	ModeImmediate
+	ModeZeroPage
+	ModeZeroPageX
+	ModeZeroPageY
+	ModeAbsolute
+	ModeAbsoluteX
+	ModeAbsoluteY
+	ModeIndirect
+	ModeIndexedIndirect
+	ModeIndirectIndexed
+	ModeRelative
+	// 65C02 only
+	ModeZeroPageIndirect
+	ModeAbsoluteIndexedIndirect
+)
+
+// Operands returns the number of operand bytes that follow the opcode
+func (mode AddressingMode) Operands() int {
+	switch mode {
+	case ModeImplied, ModeAccumulator:
+		return 0
+	case ModeAbsolute, ModeAbsoluteX, ModeAbsoluteY, ModeIndirect, ModeAbsoluteIndexedIndirect:
+		return 2
+	}
+	return 1
 }
 
 const (
 	PageCross InstructionStatus = 1 << iota
 	Branched
+	Accumulator
+	// Store is set by instructions that write their operand before they
+	// compute its address, indexed addressing then always makes a dummy read
+	Store
+	// ExtraCycle is set by instructions that take one cycle more than the
+	// table says, such as ADC and SBC in decimal mode on the 65C02
+	ExtraCycle
 )
 
 // NewInstructionTable returns a new InstructionTable
@@ -68,9 +112,144 @@ func NewInstructionTable() InstructionTable {
 			3, 6, 0, 8, 4, 4, 6, 6, 2, 5, 2, 7, 5, 5, 7, 7,
 		},
 	}
+
+	instructions.modes = make([]AddressingMode, 0x100)
+	for o := range instructions.modes {
+		instructions.modes[o] = nmosAddressingMode(OpCode(o))
+	}
 	return instructions
 }
 
+// NewCMOSInstructionTable returns a new InstructionTable with 65C02 cycles
+func NewCMOSInstructionTable() InstructionTable {
+	instructions := InstructionTable{
+		opcodes: make([]*Instruction, 0x100),
+		cycles: []uint16{
+			7, 6, 2, 1, 5, 3, 5, 1, 3, 2, 2, 1, 6, 4, 6, 1,
+			2, 5, 5, 1, 5, 4, 6, 1, 2, 4, 2, 1, 6, 4, 6, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 4, 2, 2, 1, 4, 4, 6, 1,
+			2, 5, 5, 1, 4, 4, 6, 1, 2, 4, 2, 1, 4, 4, 6, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 3, 2, 2, 1, 3, 4, 6, 1,
+			2, 5, 5, 1, 4, 4, 6, 1, 2, 4, 3, 1, 8, 4, 6, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 4, 2, 2, 1, 6, 4, 6, 1,
+			2, 5, 5, 1, 4, 4, 6, 1, 2, 4, 4, 1, 6, 4, 6, 1,
+			2, 6, 2, 1, 3, 3, 3, 1, 2, 2, 2, 1, 4, 4, 4, 1,
+			2, 6, 5, 1, 4, 4, 4, 1, 2, 5, 2, 1, 4, 5, 5, 1,
+			2, 6, 2, 1, 3, 3, 3, 1, 2, 2, 2, 1, 4, 4, 4, 1,
+			2, 5, 5, 1, 4, 4, 4, 1, 2, 4, 2, 1, 4, 4, 4, 1,
+			2, 6, 2, 1, 3, 3, 5, 1, 2, 2, 2, 1, 4, 4, 6, 1,
+			2, 5, 5, 1, 4, 4, 6, 1, 2, 4, 3, 1, 4, 4, 7, 1,
+			2, 6, 2, 1, 3, 3, 5, 1, 2, 2, 2, 1, 4, 4, 6, 1,
+			2, 5, 5, 1, 4, 4, 6, 1, 2, 4, 4, 1, 4, 4, 7, 1,
+		},
+		cyclesPageCross: []uint16{
+			7, 6, 2, 1, 5, 3, 5, 1, 3, 2, 2, 1, 6, 4, 6, 1,
+			3, 6, 5, 1, 5, 4, 6, 1, 2, 5, 2, 1, 6, 5, 7, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 4, 2, 2, 1, 4, 4, 6, 1,
+			3, 6, 5, 1, 4, 4, 6, 1, 2, 5, 2, 1, 5, 5, 7, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 3, 2, 2, 1, 3, 4, 6, 1,
+			3, 6, 5, 1, 4, 4, 6, 1, 2, 5, 3, 1, 8, 5, 7, 1,
+			6, 6, 2, 1, 3, 3, 5, 1, 4, 2, 2, 1, 6, 4, 6, 1,
+			3, 6, 5, 1, 4, 4, 6, 1, 2, 5, 4, 1, 6, 5, 7, 1,
+			3, 6, 2, 1, 3, 3, 3, 1, 2, 2, 2, 1, 4, 4, 4, 1,
+			3, 6, 5, 1, 4, 4, 4, 1, 2, 5, 2, 1, 4, 5, 5, 1,
+			2, 6, 2, 1, 3, 3, 3, 1, 2, 2, 2, 1, 4, 4, 4, 1,
+			3, 6, 5, 1, 4, 4, 4, 1, 2, 5, 2, 1, 5, 5, 5, 1,
+			2, 6, 2, 1, 3, 3, 5, 1, 2, 2, 2, 1, 4, 4, 6, 1,
+			3, 6, 5, 1, 4, 4, 6, 1, 2, 5, 3, 1, 4, 5, 7, 1,
+			2, 6, 2, 1, 3, 3, 5, 1, 2, 2, 2, 1, 4, 4, 6, 1,
+			3, 6, 5, 1, 4, 4, 6, 1, 2, 5, 4, 1, 4, 5, 7, 1,
+		},
+	}
+
+	instructions.modes = make([]AddressingMode, 0x100)
+	for o := range instructions.modes {
+		instructions.modes[o] = cmosAddressingMode(OpCode(o))
+	}
+	return instructions
+}
+
+// nmosAddressingMode decodes opcode the same way as aluAddress, rmwAddress,
+// controlAddress and illegalAddress
+func nmosAddressingMode(opcode OpCode) AddressingMode {
+	indexed := opcode&0x10 != 0
+	slot := (opcode >> 2) & 0x03
+	indexY := opcode&0xf0 == 0x90 || opcode&0xf0 == 0xb0
+
+	alu := func() AddressingMode {
+		if indexed {
+			return []AddressingMode{ModeIndirectIndexed, ModeZeroPageX, ModeAbsoluteY, ModeAbsoluteX}[slot]
+		}
+		return []AddressingMode{ModeIndexedIndirect, ModeZeroPage, ModeImmediate, ModeAbsolute}[slot]
+	}
+
+	rmw := func() AddressingMode {
+		switch {
+		case !indexed && slot == 0x00 && opcode < 0x80:
+			return ModeImplied // JAM
+		case !indexed && slot == 0x02 && opcode >= 0x80:
+			return ModeImplied // TXA, TAX, DEX, NOP
+		case !indexed:
+			return []AddressingMode{ModeImmediate, ModeZeroPage, ModeAccumulator, ModeAbsolute}[slot]
+		case slot == 0x01 && indexY:
+			return ModeZeroPageY
+		case slot == 0x03 && indexY:
+			return ModeAbsoluteY
+		}
+		return []AddressingMode{ModeImplied, ModeZeroPageX, ModeImplied, ModeAbsoluteX}[slot]
+	}
+
+	switch opcode & 0x03 {
+	case 0x00:
+		switch {
+		case opcode == 0x20:
+			return ModeAbsolute // JSR
+		case opcode == 0x6c:
+			return ModeIndirect // JMP
+		case !indexed && slot == 0x00 && opcode < 0x80:
+			return ModeImplied // BRK, RTI, RTS
+		case !indexed:
+			return []AddressingMode{ModeImmediate, ModeZeroPage, ModeImplied, ModeAbsolute}[slot]
+		}
+		return []AddressingMode{ModeRelative, ModeZeroPageX, ModeImplied, ModeAbsoluteX}[slot]
+	case 0x01:
+		return alu()
+	case 0x02:
+		return rmw()
+	}
+
+	if indexed && opcode&0x04 != 0 {
+		return rmw()
+	}
+	return alu()
+}
+
+// cmosAddressingMode is nmosAddressingMode with the 65C02's new modes and
+// undefined opcodes
+func cmosAddressingMode(opcode OpCode) AddressingMode {
+	switch {
+	case opcode&0x03 == 0x03:
+		return ModeImplied
+	case opcode&0x1f == 0x12:
+		return ModeZeroPageIndirect
+	case opcode&0x1f == 0x02:
+		return ModeImmediate
+	case opcode == 0x1a, opcode == 0x3a:
+		return ModeAccumulator // INC A, DEC A
+	case opcode == 0x14:
+		return ModeZeroPage // TRB
+	case opcode == 0x1c, opcode == 0x9c, opcode == 0x5c, opcode == 0xdc, opcode == 0xfc:
+		return ModeAbsolute
+	case opcode == 0x9e:
+		return ModeAbsoluteX // STZ
+	case opcode == 0x7c:
+		return ModeAbsoluteIndexedIndirect
+	case opcode == 0x80:
+		return ModeRelative // BRA
+	}
+	return nmosAddressingMode(opcode)
+}
+
 // Execute takes instruction from table and executes, returning number of cycles
 func (instructions InstructionTable) Execute(cpu *CPU, opcode OpCode) (cycles uint16) {
 	inst := instructions.opcodes[opcode]
@@ -89,13 +268,26 @@ func (instructions InstructionTable) Execute(cpu *CPU, opcode OpCode) (cycles ui
 		cycles++
 	}
 
+	if status&ExtraCycle != 0 {
+		cycles++
+	}
+
 	return
 }
 
+// AddInstruction adds inst to the table, filling in its addressing mode and
+// size from the table's
 func (instructions InstructionTable) AddInstruction(inst *Instruction) {
+	inst.Mode = instructions.modes[inst.OpCode]
+	inst.Size = uint8(inst.Mode.Operands()) + 1
 	instructions.opcodes[inst.OpCode] = inst
 }
 
+// Lookup returns the instruction for opcode, or nil if there is none
+func (instructions InstructionTable) Lookup(opcode OpCode) *Instruction {
+	return instructions.opcodes[opcode]
+}
+
 func (instructions InstructionTable) InitInstructions() {
 	// http://www.thealmightyguru.com/Games/Hacking/Wiki/index.php?title=6502_Opcodes
 
@@ -145,6 +337,7 @@ func (instructions InstructionTable) InitInstructions() {
 			Mneumonic: "STA",
 			OpCode:    opcode,
 			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
 				cpu.Sta(cpu.aluAddress(opcode, &status))
 				return
 			}})
@@ -156,6 +349,7 @@ func (instructions InstructionTable) InitInstructions() {
 			Mneumonic: "STX",
 			OpCode:    opcode,
 			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
 				cpu.Stx(cpu.rmwAddress(opcode, &status))
 				return
 			}})
@@ -167,8 +361,747 @@ func (instructions InstructionTable) InitInstructions() {
 			Mneumonic: "STY",
 			OpCode:    opcode,
 			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
 				cpu.Sty(cpu.controlAddress(opcode, &status))
 				return
 			}})
 	}
+
+	// Arithmetic
+	// ==========
+
+	// ADC
+	for _, o := range []OpCode{0x61, 0x65, 0x69, 0x6d, 0x71, 0x75, 0x79, 0x7d} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "ADC",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Adc(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// SBC
+	for _, o := range []OpCode{0xe1, 0xe5, 0xe9, 0xed, 0xf1, 0xf5, 0xf9, 0xfd} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "SBC",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Sbc(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// CMP
+	for _, o := range []OpCode{0xc1, 0xc5, 0xc9, 0xcd, 0xd1, 0xd5, 0xd9, 0xdd} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "CMP",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Cmp(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// Logical
+	// =======
+
+	// AND
+	for _, o := range []OpCode{0x21, 0x25, 0x29, 0x2d, 0x31, 0x35, 0x39, 0x3d} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "AND",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.And(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// ORA
+	for _, o := range []OpCode{0x01, 0x05, 0x09, 0x0d, 0x11, 0x15, 0x19, 0x1d} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "ORA",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Ora(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// EOR
+	for _, o := range []OpCode{0x41, 0x45, 0x49, 0x4d, 0x51, 0x55, 0x59, 0x5d} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "EOR",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Eor(cpu.aluAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// Shift and Rotate
+	// ================
+
+	// ASL
+	for _, o := range []OpCode{0x06, 0x0a, 0x0e, 0x16, 0x1e} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "ASL",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				address := cpu.rmwAddress(opcode, &status)
+				if status&Accumulator != 0 {
+					cpu.AslA()
+				} else {
+					cpu.Asl(address)
+				}
+				return
+			}})
+	}
+
+	// LSR
+	for _, o := range []OpCode{0x46, 0x4a, 0x4e, 0x56, 0x5e} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "LSR",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				address := cpu.rmwAddress(opcode, &status)
+				if status&Accumulator != 0 {
+					cpu.LsrA()
+				} else {
+					cpu.Lsr(address)
+				}
+				return
+			}})
+	}
+
+	// ROL
+	for _, o := range []OpCode{0x26, 0x2a, 0x2e, 0x36, 0x3e} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "ROL",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				address := cpu.rmwAddress(opcode, &status)
+				if status&Accumulator != 0 {
+					cpu.RolA()
+				} else {
+					cpu.Rol(address)
+				}
+				return
+			}})
+	}
+
+	// ROR
+	for _, o := range []OpCode{0x66, 0x6a, 0x6e, 0x76, 0x7e} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "ROR",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				address := cpu.rmwAddress(opcode, &status)
+				if status&Accumulator != 0 {
+					cpu.RorA()
+				} else {
+					cpu.Ror(address)
+				}
+				return
+			}})
+	}
+
+	// Increment and Decrement
+	// =======================
+
+	// INC
+	for _, o := range []OpCode{0xe6, 0xee, 0xf6, 0xfe} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "INC",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				cpu.Inc(cpu.rmwAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// DEC
+	for _, o := range []OpCode{0xc6, 0xce, 0xd6, 0xde} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "DEC",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				cpu.Dec(cpu.rmwAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// Branch
+	// ======
+
+	for _, b := range []struct {
+		mneumonic string
+		opcode    OpCode
+		branch    func(*CPU, uint16) InstructionStatus
+	}{
+		{"BPL", 0x10, (*CPU).Bpl},
+		{"BMI", 0x30, (*CPU).Bmi},
+		{"BVC", 0x50, (*CPU).Bvc},
+		{"BVS", 0x70, (*CPU).Bvs},
+		{"BCC", 0x90, (*CPU).Bcc},
+		{"BCS", 0xb0, (*CPU).Bcs},
+		{"BNE", 0xd0, (*CPU).Bne},
+		{"BEQ", 0xf0, (*CPU).Beq},
+	} {
+		opcode := b.opcode
+		branch := b.branch
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: b.mneumonic,
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = branch(cpu, cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// Jump
+	// ====
+
+	// JMP absolute
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "JMP",
+		OpCode:    0x4c,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.Jmp(cpu.absoluteAddress())
+			return
+		}})
+
+	// JMP indirect
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "JMP",
+		OpCode:    0x6c,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.Jmp(cpu.indirectAddress())
+			return
+		}})
+
+	// Subroutine
+	// ==========
+
+	// JSR
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "JSR",
+		OpCode:    0x20,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.Jsr()
+			return
+		}})
+
+	// RTS
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "RTS",
+		OpCode:    0x60,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.impliedAddress()
+			cpu.Rts()
+			return
+		}})
+
+	// RTI
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "RTI",
+		OpCode:    0x40,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.impliedAddress()
+			cpu.Rti()
+			return
+		}})
+
+	// Compare
+	// =======
+
+	// CPX
+	for _, o := range []OpCode{0xe0, 0xe4, 0xec} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "CPX",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Cpx(cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// CPY
+	for _, o := range []OpCode{0xc0, 0xc4, 0xcc} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "CPY",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Cpy(cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// BIT
+	for _, o := range []OpCode{0x24, 0x2c} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "BIT",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Bit(cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// Implied
+	// =======
+
+	for _, i := range []struct {
+		mneumonic string
+		opcode    OpCode
+		exec      func(*CPU)
+	}{
+		// Stack
+		{"PHA", 0x48, (*CPU).Pha},
+		{"PHP", 0x08, (*CPU).Php},
+		{"PLA", 0x68, (*CPU).Pla},
+		{"PLP", 0x28, (*CPU).Plp},
+
+		// Transfer
+		{"TAX", 0xaa, (*CPU).Tax},
+		{"TAY", 0xa8, (*CPU).Tay},
+		{"TXA", 0x8a, (*CPU).Txa},
+		{"TYA", 0x98, (*CPU).Tya},
+		{"TSX", 0xba, (*CPU).Tsx},
+		{"TXS", 0x9a, (*CPU).Txs},
+
+		// Increment and Decrement
+		{"INX", 0xe8, (*CPU).Inx},
+		{"INY", 0xc8, (*CPU).Iny},
+		{"DEX", 0xca, (*CPU).Dex},
+		{"DEY", 0x88, (*CPU).Dey},
+
+		// Flags
+		{"CLC", 0x18, (*CPU).Clc},
+		{"SEC", 0x38, (*CPU).Sec},
+		{"CLI", 0x58, (*CPU).Cli},
+		{"SEI", 0x78, (*CPU).Sei},
+		{"CLV", 0xb8, (*CPU).Clv},
+		{"CLD", 0xd8, (*CPU).Cld},
+		{"SED", 0xf8, (*CPU).Sed},
+
+		{"NOP", 0xea, (*CPU).Nop},
+	} {
+		exec := i.exec
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: i.mneumonic,
+			OpCode:    i.opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.impliedAddress()
+				exec(cpu)
+				return
+			}})
+	}
+
+	// BRK
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "BRK",
+		OpCode:    0x00,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.Brk()
+			return
+		}})
+
+	// JAM
+	// ===
+
+	// These lock up the NMOS 6502 until it is reset
+	for _, o := range []OpCode{0x02, 0x12, 0x22, 0x32, 0x42, 0x52, 0x62, 0x72, 0x92, 0xb2, 0xd2, 0xf2} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "JAM",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Jam(opcode)
+				return
+			}})
+	}
+}
+
+// InitIllegalInstructions adds the unofficial NMOS opcodes that are stable
+// enough for software to rely on, as well as the unstable SHA, SHX, SHY, TAS,
+// LAS, ANE and LXA group
+func (instructions InstructionTable) InitIllegalInstructions() {
+	// http://www.oxyron.de/html/opcodes02.html
+
+	// Read-Modify-Write
+	// =================
+
+	for _, i := range []struct {
+		mneumonic string
+		opcodes   []OpCode
+		exec      func(*CPU, uint16)
+		status    InstructionStatus
+	}{
+		{"SLO", []OpCode{0x03, 0x07, 0x0f, 0x13, 0x17, 0x1b, 0x1f}, (*CPU).Slo, Store},
+		{"RLA", []OpCode{0x23, 0x27, 0x2f, 0x33, 0x37, 0x3b, 0x3f}, (*CPU).Rla, Store},
+		{"SRE", []OpCode{0x43, 0x47, 0x4f, 0x53, 0x57, 0x5b, 0x5f}, (*CPU).Sre, Store},
+		{"RRA", []OpCode{0x63, 0x67, 0x6f, 0x73, 0x77, 0x7b, 0x7f}, (*CPU).Rra, Store},
+		{"DCP", []OpCode{0xc3, 0xc7, 0xcf, 0xd3, 0xd7, 0xdb, 0xdf}, (*CPU).Dcp, Store},
+		{"ISC", []OpCode{0xe3, 0xe7, 0xef, 0xf3, 0xf7, 0xfb, 0xff}, (*CPU).Isc, Store},
+
+		// Storage
+		{"SAX", []OpCode{0x83, 0x87, 0x8f, 0x97}, (*CPU).Sax, Store},
+		{"LAX", []OpCode{0xa3, 0xa7, 0xaf, 0xb3, 0xb7, 0xbf}, (*CPU).Lax, 0},
+
+		// Immediate
+		{"ANC", []OpCode{0x0b, 0x2b}, (*CPU).Anc, 0},
+		{"ALR", []OpCode{0x4b}, (*CPU).Alr, 0},
+		{"ARR", []OpCode{0x6b}, (*CPU).Arr, 0},
+		{"AXS", []OpCode{0xcb}, (*CPU).Axs, 0},
+		{"SBC", []OpCode{0xeb}, (*CPU).Sbc, 0},
+
+		// Unstable
+		{"ANE", []OpCode{0x8b}, (*CPU).Ane, 0},
+		{"LXA", []OpCode{0xab}, (*CPU).Lxa, 0},
+		{"LAS", []OpCode{0xbb}, (*CPU).Las, 0},
+		{"SHA", []OpCode{0x93, 0x9f}, (*CPU).Sha, Store},
+		{"SHX", []OpCode{0x9e}, (*CPU).Shx, Store},
+		{"SHY", []OpCode{0x9c}, (*CPU).Shy, Store},
+		{"TAS", []OpCode{0x9b}, (*CPU).Tas, Store},
+	} {
+		exec := i.exec
+		store := i.status
+		for _, o := range i.opcodes {
+			opcode := o
+			instructions.AddInstruction(&Instruction{
+				Mneumonic: i.mneumonic,
+				OpCode:    opcode,
+				Illegal:   true,
+				Exec: func(cpu *CPU) (status InstructionStatus) {
+					status = store
+					exec(cpu, cpu.illegalAddress(opcode, &status))
+					return
+				}})
+		}
+	}
+
+	// NOP
+	// ===
+
+	// Implied
+	for _, o := range []OpCode{0x1a, 0x3a, 0x5a, 0x7a, 0xda, 0xfa} {
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "NOP",
+			OpCode:    o,
+			Illegal:   true,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.impliedAddress()
+				cpu.Nop()
+				return
+			}})
+	}
+
+	// Immediate, zero page and absolute, the operand is read and discarded
+	for _, o := range []OpCode{
+		0x80, 0x82, 0x89, 0xc2, 0xe2,
+		0x04, 0x44, 0x64, 0x14, 0x34, 0x54, 0x74, 0xd4, 0xf4,
+		0x0c, 0x1c, 0x3c, 0x5c, 0x7c, 0xdc, 0xfc,
+	} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "NOP",
+			OpCode:    opcode,
+			Illegal:   true,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				var address uint16
+				switch opcode & 0x03 {
+				case 0x00:
+					address = cpu.controlAddress(opcode, &status)
+				case 0x01:
+					address = cpu.aluAddress(opcode, &status)
+				case 0x02:
+					address = cpu.rmwAddress(opcode, &status)
+				}
+				cpu.read(address)
+				return
+			}})
+	}
+}
+
+// InitCMOSInstructions replaces the NMOS behavior of a table initialized by
+// InitInstructions with the 65C02's. It adds the new instructions and
+// addressing modes and turns the JAM and other undefined opcodes into NOPs
+func (instructions InstructionTable) InitCMOSInstructions() {
+	// http://6502.org/tutorials/65c02opcodes.html
+
+	// Arithmetic
+	// ==========
+
+	// ADC and SBC take an extra cycle in decimal mode
+	for _, i := range []struct {
+		mneumonic string
+		opcodes   []OpCode
+		exec      func(*CPU, uint16)
+	}{
+		{"ADC", []OpCode{0x61, 0x65, 0x69, 0x6d, 0x71, 0x72, 0x75, 0x79, 0x7d}, (*CPU).Adc},
+		{"SBC", []OpCode{0xe1, 0xe5, 0xe9, 0xed, 0xf1, 0xf2, 0xf5, 0xf9, 0xfd}, (*CPU).Sbc},
+	} {
+		exec := i.exec
+		for _, o := range i.opcodes {
+			opcode := o
+			instructions.AddInstruction(&Instruction{
+				Mneumonic: i.mneumonic,
+				OpCode:    opcode,
+				Exec: func(cpu *CPU) (status InstructionStatus) {
+					address := cpu.cmosAluAddress(opcode, &status)
+					exec(cpu, address)
+					if cpu.decimalMode && cpu.Registers.P&D != 0 {
+						cpu.dummyRead(address)
+						status |= ExtraCycle
+					}
+					return
+				}})
+		}
+	}
+
+	// Zero Page Indirect
+	// ==================
+
+	for _, i := range []struct {
+		mneumonic string
+		opcode    OpCode
+		exec      func(*CPU, uint16)
+	}{
+		{"ORA", 0x12, (*CPU).Ora},
+		{"AND", 0x32, (*CPU).And},
+		{"EOR", 0x52, (*CPU).Eor},
+		{"STA", 0x92, (*CPU).Sta},
+		{"LDA", 0xb2, (*CPU).Lda},
+		{"CMP", 0xd2, (*CPU).Cmp},
+	} {
+		exec := i.exec
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: i.mneumonic,
+			OpCode:    i.opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				exec(cpu, cpu.zeroPageIndirectAddress())
+				return
+			}})
+	}
+
+	// Shift and Rotate
+	// ================
+
+	// absolute,X only takes the extra cycle when it crosses a page
+	for _, i := range []struct {
+		mneumonic string
+		opcode    OpCode
+		exec      func(*CPU, uint16)
+	}{
+		{"ASL", 0x1e, (*CPU).Asl},
+		{"ROL", 0x3e, (*CPU).Rol},
+		{"LSR", 0x5e, (*CPU).Lsr},
+		{"ROR", 0x7e, (*CPU).Ror},
+	} {
+		exec := i.exec
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: i.mneumonic,
+			OpCode:    i.opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				exec(cpu, cpu.indexedAbsoluteAddress(X, &status))
+				return
+			}})
+	}
+
+	// Storage
+	// =======
+
+	// STZ
+	for _, o := range []OpCode{0x64, 0x74, 0x9c, 0x9e} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "STZ",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				status = Store
+				var address uint16
+				switch opcode {
+				case 0x9c:
+					address = cpu.absoluteAddress()
+				case 0x9e:
+					address = cpu.indexedAbsoluteAddress(X, &status)
+				default:
+					address = cpu.controlAddress(opcode, &status)
+				}
+				cpu.Stz(address)
+				return
+			}})
+	}
+
+	// Test Bits
+	// =========
+
+	// TSB
+	for _, o := range []OpCode{0x04, 0x0c} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "TSB",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Tsb(cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// TRB
+	for _, o := range []OpCode{0x14, 0x1c} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "TRB",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				// TRB sits in the indexed slots but is not indexed
+				if opcode == 0x14 {
+					cpu.Trb(cpu.zeroPageAddress())
+				} else {
+					cpu.Trb(cpu.absoluteAddress())
+				}
+				return
+			}})
+	}
+
+	// BIT
+	for _, o := range []OpCode{0x34, 0x3c} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "BIT",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.Bit(cpu.controlAddress(opcode, &status))
+				return
+			}})
+	}
+
+	// BIT immediate
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "BIT",
+		OpCode:    0x89,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.BitImmediate(cpu.immediateAddress())
+			return
+		}})
+
+	// Branch and Jump
+	// ===============
+
+	// BRA
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "BRA",
+		OpCode:    0x80,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			status = cpu.Bra(cpu.relativeAddress())
+			return
+		}})
+
+	// JMP absolute indexed indirect
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "JMP",
+		OpCode:    0x7c,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			cpu.Jmp(cpu.indexedAbsoluteIndirectAddress())
+			return
+		}})
+
+	// Implied
+	// =======
+
+	for _, i := range []struct {
+		mneumonic string
+		opcode    OpCode
+		exec      func(*CPU)
+	}{
+		{"PHX", 0xda, (*CPU).Phx},
+		{"PLX", 0xfa, (*CPU).Plx},
+		{"PHY", 0x5a, (*CPU).Phy},
+		{"PLY", 0x7a, (*CPU).Ply},
+		{"INC", 0x1a, (*CPU).IncA},
+		{"DEC", 0x3a, (*CPU).DecA},
+	} {
+		exec := i.exec
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: i.mneumonic,
+			OpCode:    i.opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				cpu.impliedAddress()
+				exec(cpu)
+				return
+			}})
+	}
+
+	// NOP
+	// ===
+
+	// Single byte, single cycle
+	for o := 0x03; o < 0x100; o += 0x04 {
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "NOP",
+			OpCode:    OpCode(o),
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				return
+			}})
+	}
+
+	// Immediate, zero page and absolute, the operand is read and discarded
+	for _, o := range []OpCode{
+		0x02, 0x22, 0x42, 0x62, 0x82, 0xc2, 0xe2,
+		0x44, 0x54, 0xd4, 0xf4, 0xdc, 0xfc,
+	} {
+		opcode := o
+		instructions.AddInstruction(&Instruction{
+			Mneumonic: "NOP",
+			OpCode:    opcode,
+			Exec: func(cpu *CPU) (status InstructionStatus) {
+				var address uint16
+				switch opcode & 0x1f {
+				case 0x02:
+					address = cpu.immediateAddress()
+				case 0x1c:
+					address = cpu.absoluteAddress()
+				default:
+					address = cpu.controlAddress(opcode, &status)
+				}
+				cpu.read(address)
+				return
+			}})
+	}
+
+	// 0x5c reads its absolute operand and then spends five cycles reading
+	// from the top page of memory
+	instructions.AddInstruction(&Instruction{
+		Mneumonic: "NOP",
+		OpCode:    0x5c,
+		Exec: func(cpu *CPU) (status InstructionStatus) {
+			address := cpu.absoluteAddress()
+			for i := 0; i < 5; i++ {
+				cpu.dummyRead(0xff00 | address&0x00ff)
+			}
+			return
+		}})
 }
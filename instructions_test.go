@@ -25,7 +25,7 @@ func TestBadOpcodeError(t *testing.T) {
 
 	cpu.Registers.PC = 0x0100
 
-	cpu.Memory.Write(0x0100, 0x02)
+	cpu.Memory.Write(0x0100, 0x03)
 
 	_, err := cpu.Execute()
 
@@ -40,6 +40,79 @@ func TestBadOpcodeError(t *testing.T) {
 	Teardown()
 }
 
+func TestCPUJammedError(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x02)
+	cpu.Memory.Write(0x0101, 0xea)
+
+	_, err := cpu.Execute()
+
+	if _, ok := err.(CPUJammedError); !ok {
+		t.Error("Did not receive expected error type CPUJammedError")
+	}
+
+	if !cpu.Jammed() {
+		t.Error("CPU is not jammed")
+	}
+
+	cpu.Nmi = true
+	_, err = cpu.Execute()
+
+	if _, ok := err.(CPUJammedError); !ok {
+		t.Error("Did not receive expected error type CPUJammedError")
+	}
+
+	if cpu.Registers.PC != 0x0100 {
+		t.Errorf("Register PC 0x0100 != %#04x", cpu.Registers.PC)
+	}
+
+	cpu.Nmi = false
+	cpu.Rst = true
+	cpu.Memory.Write(0xfffc, 0x01)
+	cpu.Memory.Write(0xfffd, 0x01)
+
+	_, err = cpu.Execute()
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cpu.Jammed() {
+		t.Error("CPU is still jammed")
+	}
+
+	if cpu.Registers.PC != 0x0102 {
+		t.Errorf("Register PC 0x0102 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestCPUJammedReset(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x12)
+
+	cpu.Execute()
+
+	if !cpu.Jammed() {
+		t.Error("CPU is not jammed")
+	}
+
+	cpu.Reset()
+
+	if cpu.Jammed() {
+		t.Error("CPU is still jammed")
+	}
+
+	Teardown()
+}
+
 func TestLdaImmediate(t *testing.T) {
 	Setup()
 
@@ -774,3 +847,1802 @@ func TestStyAbsolute(t *testing.T) {
 
 	Teardown()
 }
+
+func TestAdcImmediate(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x02)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x03 {
+		t.Errorf("Register A 0x03 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&(C|Z|V|N) != 0 {
+		t.Errorf("Unexpected flags set %#x", cpu.Registers.P)
+	}
+
+	Teardown()
+}
+
+func TestAdcCarryFlag(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0xff
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	if cpu.Registers.P&V != 0 {
+		t.Error("V flag is set")
+	}
+
+	Teardown()
+}
+
+func TestAdcOverflowFlag(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x50
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x50)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0xa0 {
+		t.Errorf("Register A 0xa0 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&V == 0 {
+		t.Error("V flag is not set")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	Teardown()
+}
+
+func TestAdcDecimal(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x58
+	cpu.Registers.P |= C | D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x46)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x05 {
+		t.Errorf("Register A 0x05 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestAdcDecimalFlags(t *testing.T) {
+	Setup()
+
+	// 99 + 01 = 00 with carry, but the NMOS 6502 computes Z from the
+	// binary sum and N from the unadjusted result
+	cpu.Registers.A = 0x99
+	cpu.Registers.P |= D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&Z != 0 {
+		t.Error("Z flag is set")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestAdcDecimalDisabled(t *testing.T) {
+	Setup()
+
+	cpu.decimalMode = false
+	cpu.Registers.A = 0x09
+	cpu.Registers.P |= D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x0a {
+		t.Errorf("Register A 0x0a != %#x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func TestSbcImmediate(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x50
+	cpu.Registers.P |= C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe9)
+	cpu.Memory.Write(0x0101, 0xb0)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0xa0 {
+		t.Errorf("Register A 0xa0 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	if cpu.Registers.P&V == 0 {
+		t.Error("V flag is not set")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestSbcBorrow(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x05
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe9)
+	cpu.Memory.Write(0x0101, 0x04)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestSbcDecimal(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x40
+	cpu.Registers.P |= C | D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe9)
+	cpu.Memory.Write(0x0101, 0x13)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x27 {
+		t.Errorf("Register A 0x27 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestSbcDecimalBorrow(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x00
+	cpu.Registers.P |= C | D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe9)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x99 {
+		t.Errorf("Register A 0x99 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	Teardown()
+}
+
+func TestAndImmediate(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0xf0
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x29)
+	cpu.Memory.Write(0x0101, 0x0f)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestOraZeroPage(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x0f
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x05)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0xf0)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0xff {
+		t.Errorf("Register A 0xff != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestEorIndirectY(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0xff
+	cpu.Registers.Y = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x51)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x86)
+	cpu.Memory.Write(0x0085, 0x00)
+	cpu.Memory.Write(0x0087, 0x0f)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0xf0 {
+		t.Errorf("Register A 0xf0 != %#x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func TestCmpImmediate(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x40
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xc9)
+	cpu.Memory.Write(0x0101, 0x40)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	cpu.Registers.PC = 0x0100
+	cpu.Memory.Write(0x0101, 0x41)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	if cpu.Registers.A != 0x40 {
+		t.Errorf("Register A 0x40 != %#x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func TestAslAccumulator(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x81
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x0a)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 2 {
+		t.Errorf("Cycles is %v not 2", cycles)
+	}
+
+	if cpu.Registers.A != 0x02 {
+		t.Errorf("Register A 0x02 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Memory.Read(0x0000) != 0x00 {
+		t.Error("Memory was modified")
+	}
+
+	Teardown()
+}
+
+func TestAslAbsoluteX(t *testing.T) {
+	Setup()
+
+	cpu.Registers.X = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x1e)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0085, 0x40)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 7 {
+		t.Errorf("Cycles is %v not 7", cycles)
+	}
+
+	if cpu.Memory.Read(0x0085) != 0x80 {
+		t.Error("Memory is not 0x80")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestLsrAccumulator(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x4a)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestLsrZeroPage(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x46)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x80)
+
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x0084) != 0x40 {
+		t.Error("Memory is not 0x40")
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	Teardown()
+}
+
+func TestRolAccumulator(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x80
+	cpu.Registers.P |= C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x2a)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x01 {
+		t.Errorf("Register A 0x01 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestRorAccumulator(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.P |= C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6a)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x80 {
+		t.Errorf("Register A 0x80 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestRorAbsolute(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6e)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0084, 0x02)
+
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x0084) != 0x01 {
+		t.Error("Memory is not 0x01")
+	}
+
+	Teardown()
+}
+
+func TestIncZeroPage(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe6)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0xff)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Memory.Read(0x0084) != 0x00 {
+		t.Error("Memory is not 0x00")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestDecAbsoluteX(t *testing.T) {
+	Setup()
+
+	cpu.Registers.X = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xde)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0085, 0x00)
+
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x0085) != 0xff {
+		t.Error("Memory is not 0xff")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestBneNotTaken(t *testing.T) {
+	Setup()
+
+	cpu.Registers.P |= Z
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xd0)
+	cpu.Memory.Write(0x0101, 0x10)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 2 {
+		t.Errorf("Cycles is %v not 2", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0102 {
+		t.Errorf("Register PC 0x0102 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestBneTaken(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xd0)
+	cpu.Memory.Write(0x0101, 0x10)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0112 {
+		t.Errorf("Register PC 0x0112 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestBeqTakenBackwardPageCross(t *testing.T) {
+	Setup()
+
+	cpu.Registers.P |= Z
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xf0)
+	cpu.Memory.Write(0x0101, 0xfc)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 4 {
+		t.Errorf("Cycles is %v not 4", cycles)
+	}
+
+	if cpu.Registers.PC != 0x00fe {
+		t.Errorf("Register PC 0x00fe != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestBranchFlags(t *testing.T) {
+	Setup()
+
+	for _, b := range []struct {
+		opcode OpCode
+		flag   Status
+		set    bool
+	}{
+		{0x10, N, false},
+		{0x30, N, true},
+		{0x50, V, false},
+		{0x70, V, true},
+		{0x90, C, false},
+		{0xb0, C, true},
+		{0xd0, Z, false},
+		{0xf0, Z, true},
+	} {
+		for _, taken := range []bool{true, false} {
+			if b.set == taken {
+				cpu.Registers.P |= b.flag
+			} else {
+				cpu.Registers.P &= ^b.flag
+			}
+			cpu.Registers.PC = 0x0100
+
+			cpu.Memory.Write(0x0100, uint8(b.opcode))
+			cpu.Memory.Write(0x0101, 0x02)
+
+			cpu.Execute()
+
+			if taken && cpu.Registers.PC != 0x0104 {
+				t.Errorf("Opcode %#02x did not branch", uint8(b.opcode))
+			}
+
+			if !taken && cpu.Registers.PC != 0x0102 {
+				t.Errorf("Opcode %#02x branched", uint8(b.opcode))
+			}
+		}
+	}
+
+	Teardown()
+}
+
+func TestJmpAbsolute(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x4c)
+	cpu.Memory.Write(0x0101, 0x34)
+	cpu.Memory.Write(0x0102, 0x12)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestJmpIndirect(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6c)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0084, 0x34)
+	cpu.Memory.Write(0x0085, 0x12)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestJmpIndirectPageWrap(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6c)
+	cpu.Memory.Write(0x0101, 0xff)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x02ff, 0x34)
+	cpu.Memory.Write(0x0200, 0x12)
+	cpu.Memory.Write(0x0300, 0x56)
+
+	cpu.Execute()
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestJsrRts(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x20)
+	cpu.Memory.Write(0x0101, 0x00)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x0200, 0x60)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0200 {
+		t.Errorf("Register PC 0x0200 != %#04x", cpu.Registers.PC)
+	}
+
+	if cpu.Registers.SP != 0xfb {
+		t.Errorf("Register SP 0xfb != %#02x", cpu.Registers.SP)
+	}
+
+	if cpu.Memory.Read(0x01fd) != 0x01 || cpu.Memory.Read(0x01fc) != 0x02 {
+		t.Error("Return address is not 0x0102")
+	}
+
+	cycles, _ = cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0103 {
+		t.Errorf("Register PC 0x0103 != %#04x", cpu.Registers.PC)
+	}
+
+	if cpu.Registers.SP != 0xfd {
+		t.Errorf("Register SP 0xfd != %#02x", cpu.Registers.SP)
+	}
+
+	Teardown()
+}
+
+func TestRti(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+	cpu.Registers.SP = 0xfa
+
+	cpu.Memory.Write(0x0100, 0x40)
+	cpu.Memory.Write(0x01fb, uint8(C|B|N))
+	cpu.Memory.Write(0x01fc, 0x34)
+	cpu.Memory.Write(0x01fd, 0x12)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	if cpu.Registers.P != C|U|N {
+		t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, C|U|N)
+	}
+
+	Teardown()
+}
+
+func TestBrk(t *testing.T) {
+	Setup()
+
+	cpu.Registers.P = C | U
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x00)
+	cpu.Memory.Write(0xfffe, 0x34)
+	cpu.Memory.Write(0xffff, 0x12)
+
+	cycles, err := cpu.Execute()
+
+	if _, ok := err.(BrkOpCodeError); !ok {
+		t.Error("Did not receive expected error type BrkOpCodeError")
+	}
+
+	if cycles != 7 {
+		t.Errorf("Cycles is %v not 7", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	if cpu.Registers.P&I == 0 {
+		t.Error("I flag is not set")
+	}
+
+	if cpu.Memory.Read(0x01fd) != 0x01 || cpu.Memory.Read(0x01fc) != 0x02 {
+		t.Error("Return address is not 0x0102")
+	}
+
+	if Status(cpu.Memory.Read(0x01fb)) != C|B|U {
+		t.Errorf("Pushed P %#02x != %#02x", cpu.Memory.Read(0x01fb), C|B|U)
+	}
+
+	Teardown()
+}
+
+func TestPhaPla(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x80
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x48)
+	cpu.Memory.Write(0x0101, 0xa9)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0103, 0x68)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Memory.Read(0x01fd) != 0x80 {
+		t.Error("A was not pushed")
+	}
+
+	cpu.Execute()
+	cycles, _ = cpu.Execute()
+
+	if cycles != 4 {
+		t.Errorf("Cycles is %v not 4", cycles)
+	}
+
+	if cpu.Registers.A != 0x80 {
+		t.Errorf("Register A 0x80 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	if cpu.Registers.SP != 0xfd {
+		t.Errorf("Register SP 0xfd != %#02x", cpu.Registers.SP)
+	}
+
+	Teardown()
+}
+
+func TestPhpPlp(t *testing.T) {
+	Setup()
+
+	cpu.Registers.P = C | U | N
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x08)
+	cpu.Memory.Write(0x0101, 0x28)
+
+	cpu.Execute()
+
+	if Status(cpu.Memory.Read(0x01fd)) != C|B|U|N {
+		t.Errorf("Pushed P %#02x != %#02x", cpu.Memory.Read(0x01fd), C|B|U|N)
+	}
+
+	cpu.Registers.P = 0
+	cpu.Execute()
+
+	if cpu.Registers.P != C|U|N {
+		t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, C|U|N)
+	}
+
+	Teardown()
+}
+
+func TestTransfers(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x80
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xaa) // TAX
+	cpu.Memory.Write(0x0101, 0x9a) // TXS
+	cpu.Memory.Write(0x0102, 0xa8) // TAY
+	cpu.Memory.Write(0x0103, 0xa9) // LDA #$00
+	cpu.Memory.Write(0x0104, 0x00)
+	cpu.Memory.Write(0x0105, 0xba) // TSX
+
+	for i := 0; i < 5; i++ {
+		cpu.Execute()
+	}
+
+	if cpu.Registers.SP != 0x80 {
+		t.Errorf("Register SP 0x80 != %#02x", cpu.Registers.SP)
+	}
+
+	if cpu.Registers.Y != 0x80 {
+		t.Errorf("Register Y 0x80 != %#02x", cpu.Registers.Y)
+	}
+
+	if cpu.Registers.X != 0x80 {
+		t.Errorf("Register X 0x80 != %#02x", cpu.Registers.X)
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestInxDey(t *testing.T) {
+	Setup()
+
+	cpu.Registers.X = 0xff
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe8)
+	cpu.Memory.Write(0x0101, 0x88)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 2 {
+		t.Errorf("Cycles is %v not 2", cycles)
+	}
+
+	if cpu.Registers.X != 0x00 || cpu.Registers.P&Z == 0 {
+		t.Error("X did not wrap to 0x00")
+	}
+
+	cpu.Execute()
+
+	if cpu.Registers.Y != 0xff || cpu.Registers.P&N == 0 {
+		t.Error("Y did not wrap to 0xff")
+	}
+
+	Teardown()
+}
+
+func TestFlagInstructions(t *testing.T) {
+	Setup()
+
+	cpu.Registers.P = V | U
+	cpu.Registers.PC = 0x0100
+
+	for i, opcode := range []uint8{0x38, 0x78, 0xf8, 0xb8} {
+		cpu.Memory.Write(0x0100+uint16(i), opcode)
+		cpu.Execute()
+	}
+
+	if cpu.Registers.P != C|I|D|U {
+		t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, C|I|D|U)
+	}
+
+	for i, opcode := range []uint8{0x18, 0x58, 0xd8} {
+		cpu.Memory.Write(0x0104+uint16(i), opcode)
+		cpu.Execute()
+	}
+
+	if cpu.Registers.P != U {
+		t.Errorf("Register P %#02x != %#02x", cpu.Registers.P, U)
+	}
+
+	Teardown()
+}
+
+func TestBitZeroPage(t *testing.T) {
+	Setup()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x24)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0xc0)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&(Z|V|N) != Z|V|N {
+		t.Errorf("Register P %#02x does not have Z, V and N set", cpu.Registers.P)
+	}
+
+	Teardown()
+}
+
+func TestCpxCpy(t *testing.T) {
+	Setup()
+
+	cpu.Registers.X = 0x10
+	cpu.Registers.Y = 0x10
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe0)
+	cpu.Memory.Write(0x0101, 0x20)
+	cpu.Memory.Write(0x0102, 0xc0)
+	cpu.Memory.Write(0x0103, 0x10)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	cpu.Execute()
+
+	if cpu.Registers.P&(C|Z) != C|Z {
+		t.Error("C and Z flags are not set")
+	}
+
+	Teardown()
+}
+
+func TestNop(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xea)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 2 {
+		t.Errorf("Cycles is %v not 2", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0101 {
+		t.Errorf("Register PC 0x0101 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func SetupIllegal() {
+	cpu = NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithIllegalOpcodes())
+	cpu.Reset()
+	cpu.breakError = true
+}
+
+func TestIllegalOpcodesDisabled(t *testing.T) {
+	Setup()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xa7)
+	cpu.Memory.Write(0x0101, 0x84)
+
+	_, err := cpu.Execute()
+
+	if _, ok := err.(BadOpCodeError); !ok {
+		t.Error("Did not receive expected error type BadOpCodeError")
+	}
+
+	Teardown()
+}
+
+func TestLaxZeroPage(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xa7)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x80)
+
+	cycles, err := cpu.Execute()
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Registers.A != 0x80 || cpu.Registers.X != 0x80 {
+		t.Error("Registers A and X are not 0x80")
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestLaxAbsoluteY(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.Y = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xbf)
+	cpu.Memory.Write(0x0101, 0xff)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x0300, 0x11)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Registers.A != 0x11 || cpu.Registers.X != 0x11 {
+		t.Error("Registers A and X are not 0x11")
+	}
+
+	Teardown()
+}
+
+func TestSaxZeroPageY(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0xf0
+	cpu.Registers.X = 0x3c
+	cpu.Registers.Y = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x97)
+	cpu.Memory.Write(0x0101, 0x84)
+
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x0085) != 0x30 {
+		t.Error("Memory is not 0x30")
+	}
+
+	Teardown()
+}
+
+func TestSloZeroPage(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x07)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x81)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Memory.Read(0x0084) != 0x02 {
+		t.Error("Memory is not 0x02")
+	}
+
+	if cpu.Registers.A != 0x03 {
+		t.Errorf("Register A 0x03 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestDcpAbsolute(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0x40
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xcf)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0102, 0x00)
+	cpu.Memory.Write(0x0084, 0x41)
+
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x0084) != 0x40 {
+		t.Error("Memory is not 0x40")
+	}
+
+	if cpu.Registers.P&(C|Z) != C|Z {
+		t.Error("C and Z flags are not set")
+	}
+
+	Teardown()
+}
+
+func TestIscIndirectY(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0x10
+	cpu.Registers.Y = 1
+	cpu.Registers.P |= C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xf3)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x86)
+	cpu.Memory.Write(0x0085, 0x00)
+	cpu.Memory.Write(0x0087, 0x0f)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 8 {
+		t.Errorf("Cycles is %v not 8", cycles)
+	}
+
+	if cpu.Memory.Read(0x0087) != 0x10 {
+		t.Error("Memory is not 0x10")
+	}
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func TestArrImmediate(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0xff
+	cpu.Registers.P |= C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6b)
+	cpu.Memory.Write(0x0101, 0xc0)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0xe0 {
+		t.Errorf("Register A 0xe0 != %#x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	if cpu.Registers.P&V != 0 {
+		t.Error("V flag is set")
+	}
+
+	Teardown()
+}
+
+func TestAxsImmediate(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.A = 0x0f
+	cpu.Registers.X = 0x3c
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xcb)
+	cpu.Memory.Write(0x0101, 0x0d)
+
+	cpu.Execute()
+
+	if cpu.Registers.X != 0xff {
+		t.Errorf("Register X 0xff != %#x", cpu.Registers.X)
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	Teardown()
+}
+
+func TestShxPageCross(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.X = 0x01
+	cpu.Registers.Y = 0x02
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0x9e)
+	cpu.Memory.Write(0x0201, 0xff)
+	cpu.Memory.Write(0x0202, 0x04)
+
+	cpu.Execute()
+
+	// 0x04ff + 2 crosses into page 0x05, the stored value X & 0x05 also
+	// replaces the high byte of the address
+	if cpu.Memory.Read(0x0101) != 0x01 {
+		t.Error("Memory at 0x0101 is not 0x01")
+	}
+
+	if cpu.Memory.Read(0x0501) != 0x00 {
+		t.Error("Memory at 0x0501 was written")
+	}
+
+	Teardown()
+}
+
+func TestShyAbsoluteX(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.X = 0x05
+	cpu.Registers.Y = 0x14
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0x9c)
+	cpu.Memory.Write(0x0201, 0x00)
+	cpu.Memory.Write(0x0202, 0x03)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	// Y & (0x03 + 1)
+	if cpu.Memory.Read(0x0305) != 0x04 {
+		t.Errorf("Memory at 0x0305 0x04 != %#02x", cpu.Memory.Read(0x0305))
+	}
+
+	if cpu.Memory.Read(0x0314) != 0x00 {
+		t.Error("Memory at 0x0314 was written")
+	}
+
+	Teardown()
+}
+
+func TestShyPageCross(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.X = 0x02
+	cpu.Registers.Y = 0x01
+	cpu.Registers.PC = 0x0200
+
+	cpu.Memory.Write(0x0200, 0x9c)
+	cpu.Memory.Write(0x0201, 0xff)
+	cpu.Memory.Write(0x0202, 0x04)
+
+	cpu.Execute()
+
+	// 0x04ff + 2 crosses into page 0x05, the stored value Y & 0x05 also
+	// replaces the high byte of the address
+	if cpu.Memory.Read(0x0101) != 0x01 {
+		t.Error("Memory at 0x0101 is not 0x01")
+	}
+
+	if cpu.Memory.Read(0x0501) != 0x00 {
+		t.Error("Memory at 0x0501 was written")
+	}
+
+	Teardown()
+}
+
+func TestNopAbsoluteX(t *testing.T) {
+	SetupIllegal()
+
+	cpu.Registers.X = 1
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x1c)
+	cpu.Memory.Write(0x0101, 0xff)
+	cpu.Memory.Write(0x0102, 0x02)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0103 {
+		t.Errorf("Register PC 0x0103 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestRicoh2A03Decimal(t *testing.T) {
+	cpu = NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithVariant(Ricoh2A03))
+	cpu.Reset()
+
+	cpu.Registers.A = 0x09
+	cpu.Registers.P |= D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x05)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x0e {
+		t.Errorf("Register A 0x0e != %#02x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func SetupCMOS() {
+	cpu = NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithVariant(CMOS65C02))
+	cpu.Reset()
+	cpu.breakError = true
+}
+
+func TestCMOSUndefinedOpcodes(t *testing.T) {
+	SetupCMOS()
+
+	for o := 0; o < 0x100; o++ {
+		if cpu.Instructions.opcodes[o] == nil {
+			t.Errorf("Opcode %#02x is not defined", o)
+		}
+	}
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x02)
+	cpu.Memory.Write(0x0101, 0xff)
+	cpu.Memory.Write(0x0102, 0x03)
+
+	cycles, err := cpu.Execute()
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cycles != 2 || cpu.Registers.PC != 0x0102 {
+		t.Errorf("Opcode 0x02 took %v cycles to PC %#04x", cycles, cpu.Registers.PC)
+	}
+
+	cycles, _ = cpu.Execute()
+
+	if cycles != 1 || cpu.Registers.PC != 0x0103 {
+		t.Errorf("Opcode 0x03 took %v cycles to PC %#04x", cycles, cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestCMOSJmpIndirect(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x6c)
+	cpu.Memory.Write(0x0101, 0xff)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x02ff, 0x34)
+	cpu.Memory.Write(0x0300, 0x12)
+	cpu.Memory.Write(0x0200, 0x56)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestCMOSJmpAbsoluteIndexedIndirect(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.X = 0x02
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x7c)
+	cpu.Memory.Write(0x0101, 0x00)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x0202, 0x34)
+	cpu.Memory.Write(0x0203, 0x12)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Registers.PC != 0x1234 {
+		t.Errorf("Register PC 0x1234 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestCMOSBra(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x80)
+	cpu.Memory.Write(0x0101, 0x10)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Registers.PC != 0x0112 {
+		t.Errorf("Register PC 0x0112 != %#04x", cpu.Registers.PC)
+	}
+
+	Teardown()
+}
+
+func TestCMOSStzAbsoluteX(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.X = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x9e)
+	cpu.Memory.Write(0x0101, 0x00)
+	cpu.Memory.Write(0x0102, 0x02)
+	cpu.Memory.Write(0x0201, 0xff)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Memory.Read(0x0201) != 0x00 {
+		t.Error("Memory at 0x0201 is not 0x00")
+	}
+
+	Teardown()
+}
+
+func TestCMOSTsbTrb(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.A = 0x0f
+	cpu.Registers.PC = 0x0100
+
+	// TSB $84
+	cpu.Memory.Write(0x0100, 0x04)
+	cpu.Memory.Write(0x0101, 0x84)
+	// TRB $0200
+	cpu.Memory.Write(0x0102, 0x1c)
+	cpu.Memory.Write(0x0103, 0x00)
+	cpu.Memory.Write(0x0104, 0x02)
+
+	cpu.Memory.Write(0x0084, 0xf0)
+	cpu.Memory.Write(0x0200, 0xff)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Memory.Read(0x0084) != 0xff {
+		t.Error("Memory at 0x0084 is not 0xff")
+	}
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	cycles, _ = cpu.Execute()
+
+	if cycles != 6 {
+		t.Errorf("Cycles is %v not 6", cycles)
+	}
+
+	if cpu.Memory.Read(0x0200) != 0xf0 {
+		t.Error("Memory at 0x0200 is not 0xf0")
+	}
+
+	if cpu.Registers.P&Z != 0 {
+		t.Error("Z flag is set")
+	}
+
+	Teardown()
+}
+
+func TestCMOSPhxPly(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.X = 0x80
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xda)
+	cpu.Memory.Write(0x0101, 0x7a)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	cycles, _ = cpu.Execute()
+
+	if cycles != 4 {
+		t.Errorf("Cycles is %v not 4", cycles)
+	}
+
+	if cpu.Registers.Y != 0x80 {
+		t.Errorf("Register Y 0x80 != %#02x", cpu.Registers.Y)
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestCMOSLdaZeroPageIndirect(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xb2)
+	cpu.Memory.Write(0x0101, 0x84)
+	cpu.Memory.Write(0x0084, 0x00)
+	cpu.Memory.Write(0x0085, 0x02)
+	cpu.Memory.Write(0x0200, 0x42)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 5 {
+		t.Errorf("Cycles is %v not 5", cycles)
+	}
+
+	if cpu.Registers.A != 0x42 {
+		t.Errorf("Register A 0x42 != %#02x", cpu.Registers.A)
+	}
+
+	Teardown()
+}
+
+func TestCMOSBitImmediate(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.A = 0x01
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x89)
+	cpu.Memory.Write(0x0101, 0xc0)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	if cpu.Registers.P&(N|V) != 0 {
+		t.Error("N or V flag is set")
+	}
+
+	Teardown()
+}
+
+func TestCMOSAdcDecimal(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.A = 0x99
+	cpu.Registers.P |= D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x69)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cycles, _ := cpu.Execute()
+
+	if cycles != 3 {
+		t.Errorf("Cycles is %v not 3", cycles)
+	}
+
+	if cpu.Registers.A != 0x00 {
+		t.Errorf("Register A 0x00 != %#02x", cpu.Registers.A)
+	}
+
+	// the NMOS 6502 computes Z from the binary sum 0x9a
+	if cpu.Registers.P&Z == 0 {
+		t.Error("Z flag is not set")
+	}
+
+	if cpu.Registers.P&C == 0 {
+		t.Error("C flag is not set")
+	}
+
+	Teardown()
+}
+
+func TestCMOSSbcDecimal(t *testing.T) {
+	SetupCMOS()
+
+	cpu.Registers.A = 0x00
+	cpu.Registers.P |= D | C
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0xe9)
+	cpu.Memory.Write(0x0101, 0x01)
+
+	cpu.Execute()
+
+	if cpu.Registers.A != 0x99 {
+		t.Errorf("Register A 0x99 != %#02x", cpu.Registers.A)
+	}
+
+	if cpu.Registers.P&N == 0 {
+		t.Error("N flag is not set")
+	}
+
+	if cpu.Registers.P&C != 0 {
+		t.Error("C flag is set")
+	}
+
+	Teardown()
+}
+
+func TestCMOSBrkClearsDecimal(t *testing.T) {
+	SetupCMOS()
+	cpu.breakError = false
+
+	cpu.Registers.P |= D
+	cpu.Registers.PC = 0x0100
+
+	cpu.Memory.Write(0x0100, 0x00)
+
+	cpu.Execute()
+
+	if cpu.Registers.P&D != 0 {
+		t.Error("D flag is set")
+	}
+
+	if cpu.Memory.Read(0x01fb)&uint8(D) == 0 {
+		t.Error("D flag was not pushed")
+	}
+
+	Teardown()
+}
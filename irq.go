@@ -0,0 +1,89 @@
+package cpu
+
+import (
+	"errors"
+	"strings"
+)
+
+// IrqSource identifies a device that can assert the shared IRQ line. Each
+// source is a single bit, the line is asserted while any of them is
+type IrqSource uint32
+
+const (
+	// IrqExternal is a generic source for devices without their own
+	IrqExternal IrqSource = 1 << iota
+	// IrqFrameCounter APU frame counter
+	IrqFrameCounter
+	// IrqDmc APU delta modulation channel
+	IrqDmc
+	// IrqMapper cartridge mapper
+	IrqMapper
+)
+
+// ErrNoIrqSources is returned by NewSource when all 32 sources are in use
+var ErrNoIrqSources = errors.New("no IRQ sources left")
+
+var irqSourceNames = []string{"External", "FrameCounter", "Dmc", "Mapper"}
+
+// IrqLine is the 6502's level triggered IRQ input, shared by every device
+// that can request an interrupt. Devices assert and release their own
+// sources independently and the CPU sees the or of all of them
+type IrqLine struct {
+	asserted IrqSource
+	names    []string
+}
+
+// Assert pulls the line low on behalf of source
+func (line *IrqLine) Assert(source IrqSource) {
+	line.asserted |= source
+}
+
+// Release lets go of the line on behalf of source. The line stays asserted
+// while any other source holds it
+func (line *IrqLine) Release(source IrqSource) {
+	line.asserted &= ^source
+}
+
+// Asserted returns true if any source is holding the line
+func (line *IrqLine) Asserted() bool {
+	return line.asserted != 0
+}
+
+// Sources returns the sources currently holding the line
+func (line *IrqLine) Sources() IrqSource {
+	return line.asserted
+}
+
+// NewSource allocates a source for a device that has no predefined one.
+// There are 32 sources including the predefined ones
+func (line *IrqLine) NewSource(name string) (IrqSource, error) {
+	if line.names == nil {
+		line.names = append(line.names, irqSourceNames...)
+	}
+	if len(line.names) == 32 {
+		return 0, ErrNoIrqSources
+	}
+	line.names = append(line.names, name)
+	return 1 << uint(len(line.names)-1), nil
+}
+
+// Name returns the names of the sources in source, separated by |
+func (line *IrqLine) Name(source IrqSource) string {
+	names := line.names
+	if names == nil {
+		names = irqSourceNames
+	}
+
+	var result []string
+	for i, name := range names {
+		if source&(1<<uint(i)) != 0 {
+			result = append(result, name)
+		}
+	}
+	return strings.Join(result, "|")
+}
+
+// String returns the names of the sources currently holding the line
+func (line *IrqLine) String() string {
+	return line.Name(line.asserted)
+}
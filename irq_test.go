@@ -0,0 +1,87 @@
+package cpu
+
+import "testing"
+
+func TestIrqLineSources(t *testing.T) {
+	var line IrqLine
+
+	if line.Asserted() {
+		t.Error("Line is asserted")
+	}
+
+	line.Assert(IrqFrameCounter)
+	line.Assert(IrqMapper)
+
+	if !line.Asserted() {
+		t.Error("Line is not asserted")
+	}
+
+	if line.Sources() != IrqFrameCounter|IrqMapper {
+		t.Errorf("Sources %#x != %#x", line.Sources(), IrqFrameCounter|IrqMapper)
+	}
+
+	if line.String() != "FrameCounter|Mapper" {
+		t.Errorf("Line is held by %q", line.String())
+	}
+
+	line.Release(IrqFrameCounter)
+
+	if !line.Asserted() {
+		t.Error("Line was released while the mapper holds it")
+	}
+
+	line.Release(IrqMapper)
+
+	if line.Asserted() {
+		t.Error("Line is asserted")
+	}
+}
+
+func TestIrqLineNewSource(t *testing.T) {
+	var line IrqLine
+
+	source, err := line.NewSource("Expansion")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source&(IrqExternal|IrqFrameCounter|IrqDmc|IrqMapper) != 0 {
+		t.Errorf("Source %#x overlaps a predefined source", source)
+	}
+
+	line.Assert(source | IrqDmc)
+
+	if line.String() != "Dmc|Expansion" {
+		t.Errorf("Line is held by %q", line.String())
+	}
+
+	for i := 0; i < 27; i++ {
+		if _, err = line.NewSource("Device"); err != nil {
+			t.Fatalf("Source %d: %v", i+6, err)
+		}
+	}
+
+	if _, err = line.NewSource("Device"); err != ErrNoIrqSources {
+		t.Errorf("NewSource returned %v with all 32 sources in use", err)
+	}
+}
+
+func TestIrqLineSharedByCPU(t *testing.T) {
+	cpu := setupInterruptTest()
+	cpu.Memory.Write(0x4000, 0x40) // RTI
+
+	cpu.Irq.Assert(IrqDmc)
+	cpu.Irq.Assert(IrqMapper)
+	cpu.Irq.Release(IrqDmc)
+
+	cpu.Execute()
+	cpu.Execute()
+
+	if cpu.Memory.Read(0x01fd) != 0x02 || cpu.Memory.Read(0x01fc) != 0x01 {
+		t.Error("IRQ was not serviced while the mapper holds the line")
+	}
+
+	if cpu.Irq.Sources() != IrqMapper {
+		t.Errorf("Sources %#x != %#x", cpu.Irq.Sources(), IrqMapper)
+	}
+}
@@ -0,0 +1,86 @@
+package cpu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// klausTest describes one of Klaus Dormann's test binaries and how to tell
+// whether it passed once it is trapped
+type klausTest struct {
+	file    string
+	variant Variant
+	load    uint16 // address the binary is loaded at
+	start   uint16 // initial PC
+	success uint16 // PC of the success trap, 0 if result alone tells
+	result  uint16 // address of the failing test number or error flag
+	brk     bool   // the test ends with BRK instead of a trap
+}
+
+var klausTests = []klausTest{
+	{"6502_functional_test.bin", NMOS6502, 0x0000, 0x0400, 0x3469, 0x0200, false},
+	{"6502_decimal_test.bin", NMOS6502, 0x0200, 0x0200, 0x0000, 0x000b, true},
+}
+
+// klausMaxInstructions is well above the ~30 million instructions the
+// functional test takes to complete
+const klausMaxInstructions = 100000000
+
+func TestKlaus(t *testing.T) {
+	for _, test := range klausTests {
+		test := test
+		t.Run(test.file, func(t *testing.T) {
+			runKlausTest(t, test)
+		})
+	}
+}
+
+func runKlausTest(t *testing.T, test klausTest) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "klaus", test.file))
+	if os.IsNotExist(err) {
+		t.Skipf("%v not found", test.file)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	for i, value := range data {
+		mem.Write(test.load+uint16(i), value)
+	}
+
+	cpu := NewCPU(mem, WithVariant(test.variant))
+	cpu.breakError = test.brk
+	cpu.Registers.PC = test.start
+
+	// run until the program traps itself in a jump or branch to itself
+	var pc uint16
+	for i := 0; i < klausMaxInstructions; i++ {
+		pc = cpu.Registers.PC
+		if _, err = cpu.Execute(); err != nil || cpu.Registers.PC == pc {
+			break
+		}
+	}
+
+	if _, ok := err.(BrkOpCodeError); err != nil && !(ok && test.brk) {
+		t.Fatalf("%v at %#04x, test %#02x", err, pc, mem.Read(test.result))
+	}
+
+	if cpu.Registers.PC != pc && err == nil {
+		t.Fatalf("Not trapped after %v instructions, PC is %#04x",
+			klausMaxInstructions, cpu.Registers.PC)
+	}
+
+	if test.success != 0 && pc != test.success {
+		t.Errorf("Trapped at %#04x, test %#02x failed", pc, mem.Read(test.result))
+	}
+
+	if test.success == 0 && mem.Read(test.result) != 0 {
+		t.Errorf("Ended at %#04x with error %#02x", pc, mem.Read(test.result))
+	}
+}
@@ -1,9 +1,6 @@
 package cpu
 
-import (
-	"io"
-	"os"
-)
+import "math/rand"
 
 const DEFAULT_MEMORY_SIZE uint32 = 65536
 
@@ -13,18 +10,109 @@ type Memory interface {
 	Write(address uint16, value uint8) (oldValue uint8)
 }
 
+// DebugMemory is implemented by memory that debuggers, tracers and loaders
+// can inspect and change without the side effects of Read and Write, such as
+// clearing the vblank flag by reading PPUSTATUS or ignoring writes to ROM.
+// Every device should implement it, tooling can't see memory that doesn't
+type DebugMemory interface {
+	Memory
+	Peek(address uint16) (value uint8)
+	Poke(address uint16, value uint8) (oldValue uint8)
+}
+
+// Peek reads address with Peek if mem is a DebugMemory. Memory that is not
+// can't be read without side effects, so it is never read and Peek returns 0
+func Peek(mem Memory, address uint16) uint8 {
+	if d, ok := mem.(DebugMemory); ok {
+		return d.Peek(address)
+	}
+	return 0
+}
+
+// Poke writes address with Poke if mem is a DebugMemory. Memory that is not
+// is left alone and Poke returns 0
+func Poke(mem Memory, address uint16, value uint8) (oldValue uint8) {
+	if d, ok := mem.(DebugMemory); ok {
+		return d.Poke(address, value)
+	}
+	return 0
+}
+
+// FetchMemory is implemented by memory that needs to tell the opcode fetch
+// of an instruction apart from the reads of its operands and data. Execute
+// fetches opcodes with Fetch instead of Read
+type FetchMemory interface {
+	Memory
+	Fetch(address uint16) (value uint8)
+}
+
+// Fetch reads address with Fetch if mem is a FetchMemory, or else with Read
+func Fetch(mem Memory, address uint16) uint8 {
+	if f, ok := mem.(FetchMemory); ok {
+		return f.Fetch(address)
+	}
+	return mem.Read(address)
+}
+
+// Fill sets the contents of memory at power on
+type Fill func(m []uint8)
+
+// FillZeros sets every byte to 0x00
+func FillZeros() Fill {
+	return FillPattern(0x00)
+}
+
+// FillOnes sets every byte to 0xff
+func FillOnes() Fill {
+	return FillPattern(0xff)
+}
+
+// FillPattern repeats pattern over the whole memory, e.g. FillPattern(0x00,
+// 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff) as seen on many NES consoles.
+// An empty pattern fills with zeros
+func FillPattern(pattern ...uint8) Fill {
+	if len(pattern) == 0 {
+		pattern = []uint8{0x00}
+	}
+	return func(m []uint8) {
+		for i := range m {
+			m[i] = pattern[i%len(pattern)]
+		}
+	}
+}
+
+// FillRandom sets every byte to a random value, the same seed always
+// produces the same contents
+func FillRandom(seed int64) Fill {
+	return func(m []uint8) {
+		r := rand.New(rand.NewSource(seed))
+		for i := range m {
+			m[i] = uint8(r.Intn(0x100))
+		}
+	}
+}
+
 type BasicMemory struct {
 	m              []uint8
+	fill           Fill
 	disableReads   bool
 	disabledWrites bool
 }
 
 func NewBasicMemory(size uint32) *BasicMemory {
 	return &BasicMemory{
-		m: make([]uint8, size),
+		m:    make([]uint8, size),
+		fill: FillZeros(),
 	}
 }
 
+// PowerOnFill sets how Reset fills memory
+func (mem *BasicMemory) PowerOnFill(fill Fill) {
+	mem.fill = fill
+}
+
+// DisableReads makes every read return 0xff. A Bus emulates reads from
+// unmapped addresses more faithfully with the open bus
 func (mem *BasicMemory) DisableReads() {
 	mem.disableReads = true
 }
@@ -41,10 +129,10 @@ func (mem *BasicMemory) EnableWrites() {
 	mem.disabledWrites = false
 }
 
+// Reset sets memory to its power on contents, zeros unless changed with
+// PowerOnFill
 func (mem *BasicMemory) Reset() {
-	for i := range mem.m {
-		mem.m[i] = 0x00
-	}
+	mem.fill(mem.m)
 }
 
 func (mem *BasicMemory) Read(address uint16) (value uint8) {
@@ -64,56 +152,21 @@ func (mem *BasicMemory) Write(address uint16, value uint8) (oldValue uint8) {
 	return
 }
 
+// Peek reads address even if reads are disabled
+func (mem *BasicMemory) Peek(address uint16) (value uint8) {
+	return mem.m[address]
+}
+
+// Poke writes address even if writes are disabled
+func (mem *BasicMemory) Poke(address uint16, value uint8) (oldValue uint8) {
+	oldValue = mem.m[address]
+	mem.m[address] = value
+	return
+}
+
 // SamePage returns true if the two addresses are located on the same
 // page in memory. Two addresses are on the same page if their high
 //  bytes are both the same 0x0101 and 0x0103 but not 0x0101 and 0x0203
 func SamePage(addr1 uint16, addr2 uint16) bool {
 	return (addr1^addr2)>>8 == 0
 }
-
-func (mem *BasicMemory) load(path string) {
-	fi, err := os.Open(path)
-
-	if err != nil {
-		panic(err)
-	}
-
-	defer func() {
-		if err := fi.Close(); err != nil {
-			panic(err)
-		}
-	}()
-
-	total := 0
-	buf := make([]byte, 65536)
-
-	for {
-		n, err := fi.Read(buf)
-
-		if err != nil && err != io.EOF {
-			panic(err)
-		}
-
-		if n == 0 {
-			break
-		}
-
-		total++
-	}
-
-	j := 0xc000
-
-	for i, b := range buf {
-		if i <= 15 {
-			continue
-		}
-
-		mem.m[j] = b
-		j++
-
-		if j == 0xffff {
-			break
-		}
-	}
-	return
-}
@@ -19,3 +19,101 @@ func TestSamePage(t *testing.T) {
 		}
 	}
 }
+
+func TestPowerOnFill(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	mem.Write(0x0000, 0x12)
+	mem.Reset()
+
+	if mem.Read(0x0000) != 0x00 {
+		t.Error("Memory was not cleared")
+	}
+
+	mem.PowerOnFill(FillOnes())
+	mem.Reset()
+
+	if mem.Read(0x0000) != 0xff || mem.Read(0xffff) != 0xff {
+		t.Error("Memory was not filled with 0xff")
+	}
+
+	mem.PowerOnFill(FillPattern(0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff))
+	mem.Reset()
+
+	if mem.Read(0x0003) != 0x00 || mem.Read(0x0004) != 0xff || mem.Read(0x0008) != 0x00 {
+		t.Error("Memory was not filled with the pattern")
+	}
+
+	mem.PowerOnFill(FillPattern())
+	mem.Reset()
+
+	if mem.Read(0x0004) != 0x00 {
+		t.Error("Memory was not cleared by an empty pattern")
+	}
+}
+
+func TestPowerOnFillRandom(t *testing.T) {
+	a := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	b := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	a.PowerOnFill(FillRandom(42))
+	b.PowerOnFill(FillRandom(42))
+	a.Reset()
+	b.Reset()
+
+	different := false
+	for address := 0; address < 0x100; address++ {
+		if a.Read(uint16(address)) != b.Read(uint16(address)) {
+			t.Fatalf("Memory differs at %#04x with the same seed", address)
+		}
+		if a.Read(uint16(address)) != a.Read(0x0000) {
+			different = true
+		}
+	}
+
+	if !different {
+		t.Error("Memory is not random")
+	}
+}
+
+func TestPeekPoke(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	mem.DisableReads()
+	mem.DisabledWrites()
+
+	if Poke(mem, 0x1234, 0x56) != 0x00 || mem.Write(0x1234, 0x78) != 0x00 {
+		t.Error("Poke or Write returned an old value")
+	}
+
+	if Peek(mem, 0x1234) != 0x56 || mem.Read(0x1234) != 0xff {
+		t.Errorf("Memory at 0x1234 0x56 != %#02x", Peek(mem, 0x1234))
+	}
+}
+
+// plainMemory is a device without Peek and Poke that counts its accesses
+type plainMemory struct {
+	reads  int
+	writes int
+}
+
+func (mem *plainMemory) Reset() {}
+
+func (mem *plainMemory) Read(address uint16) uint8 {
+	mem.reads++
+	return 0x42
+}
+
+func (mem *plainMemory) Write(address uint16, value uint8) uint8 {
+	mem.writes++
+	return 0
+}
+
+func TestPeekPokeWithoutDebugMemory(t *testing.T) {
+	mem := &plainMemory{}
+
+	if Peek(mem, 0x2002) != 0x00 || Poke(mem, 0x2007, 0x12) != 0x00 {
+		t.Error("Peek or Poke returned a value")
+	}
+
+	if mem.reads != 0 || mem.writes != 0 {
+		t.Errorf("Peek and Poke made %d reads and %d writes", mem.reads, mem.writes)
+	}
+}
@@ -0,0 +1,133 @@
+package cpu
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mpicard/gones/cartridge"
+)
+
+// TestNestest runs nestest.nes in automation mode from 0xc000 and compares
+// the trace with nestest.log line by line
+func TestNestest(t *testing.T) {
+	rom, err := os.Open(filepath.Join("testdata", "nestest", "nestest.nes"))
+	if os.IsNotExist(err) {
+		t.Skip("nestest.nes not found")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rom.Close()
+
+	log, err := os.Open(filepath.Join("testdata", "nestest", "nestest.log"))
+	if os.IsNotExist(err) {
+		t.Skip("nestest.log not found")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	cart, err := cartridge.Read(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrom, err := cartridge.NewNROM(cart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the PPU, APU and I/O registers are left unmapped and read as 0xff,
+	// which is what they read as in nestest.log
+	mem := NewBus(WithUnmappedValue(0xff))
+	mem.Map(Mapping{Name: "RAM", Start: 0x0000, End: 0x1fff, Mask: 0x07ff, Device: NewBasicMemory(0x0800)})
+	mem.Map(Mapping{Name: "PRG", Start: 0x8000, End: 0xffff, Device: nrom})
+
+	cpu := NewCPU(mem, WithVariant(Ricoh2A03), WithIllegalOpcodes())
+	cpu.PowerOn()
+	cpu.Registers.PC = 0xc000
+
+	tracer := NewTracer(nil)
+	scanner := bufio.NewScanner(log)
+	for line := 1; scanner.Scan(); line++ {
+		if trace := tracer.Line(cpu); trace != scanner.Text() {
+			t.Fatalf("Line %v diverges\n%v\n!=\n%v", line, trace, scanner.Text())
+		}
+
+		if _, err = cpu.Execute(); err != nil {
+			t.Fatalf("Line %v: %v", line, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// nestest reports the number of the failing official and unofficial
+	// test at 0x02 and 0x03
+	if mem.Read(0x0002) != 0x00 || mem.Read(0x0003) != 0x00 {
+		t.Errorf("nestest failed with %#02x %#02x", mem.Read(0x0002), mem.Read(0x0003))
+	}
+}
+
+// nestestStart is the start of nestest.log, transcribed so that the tracer is
+// checked against the real log even without the ROM
+var nestestStart = []string{
+	"C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+	"C5F5  A2 00     LDX #$00                        A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 30 CYC:10",
+	"C5F7  86 00     STX $00 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 36 CYC:12",
+	"C5F9  86 10     STX $10 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 45 CYC:15",
+	"C5FB  86 11     STX $11 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 54 CYC:18",
+	"C5FD  20 2D C7  JSR $C72D                       A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 63 CYC:21",
+	"C72D  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 81 CYC:27",
+	"C72E  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 87 CYC:29",
+	"C72F  B0 04     BCS $C735                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0, 93 CYC:31",
+	"C735  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,102 CYC:34",
+	"C736  18        CLC                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,108 CYC:36",
+	"C737  B0 03     BCS $C73C                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,114 CYC:38",
+	"C739  4C 42 C7  JMP $C742                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,120 CYC:40",
+	"C742  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,129 CYC:43",
+	"C743  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,135 CYC:45",
+	"C744  90 03     BCC $C749                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0,141 CYC:47",
+	"C746  4C 4B C7  JMP $C74B                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0,147 CYC:49",
+	"C74B  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,156 CYC:52",
+	"C74C  18        CLC                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,162 CYC:54",
+	"C74D  90 03     BCC $C752                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,168 CYC:56",
+	"C752  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,177 CYC:59",
+}
+
+// TestNestestStart loads the instructions of nestestStart from their
+// address and bytes and compares the trace with it
+func TestNestestStart(t *testing.T) {
+	cpu := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithVariant(Ricoh2A03), WithIllegalOpcodes())
+	cpu.PowerOn()
+	cpu.Registers.PC = 0xc000
+
+	for _, line := range nestestStart {
+		address, err := strconv.ParseUint(line[0:4], 16, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, field := range strings.Fields(line[6:14]) {
+			value, err := strconv.ParseUint(field, 16, 8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Poke(cpu.Memory, uint16(address)+uint16(i), uint8(value))
+		}
+	}
+
+	tracer := NewTracer(nil)
+	for i, expected := range nestestStart {
+		if trace := tracer.Line(cpu); trace != expected {
+			t.Fatalf("Line %v diverges\n%v\n!=\n%v", i+1, trace, expected)
+		}
+
+		if _, err := cpu.Execute(); err != nil {
+			t.Fatalf("Line %v: %v", i+1, err)
+		}
+	}
+}
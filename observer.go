@@ -0,0 +1,109 @@
+package cpu
+
+// AccessKind is a kind of memory access seen by an Observer
+type AccessKind uint8
+
+const (
+	// AccessRead is a read of an operand or data, or a dummy read
+	AccessRead AccessKind = 1 << iota
+	// AccessWrite is a write, including dummy writes
+	AccessWrite
+	// AccessFetch is the read of an opcode by Execute
+	AccessFetch
+)
+
+// Hook is called with the address, the value read or written, and the kind
+// of every access it observes
+type Hook func(address uint16, value uint8, kind AccessKind)
+
+type hook struct {
+	id    int
+	start uint16
+	end   uint16
+	kinds AccessKind
+	fn    Hook
+}
+
+// Observer wraps a Memory and calls hooks for the accesses to it. With no
+// hooks for a kind of access it only adds a comparison to each one
+type Observer struct {
+	Memory
+	hooks  []hook
+	kinds  AccessKind // of all the hooks
+	nextID int
+}
+
+// NewObserver wraps mem. Give the Observer to the CPU in place of mem so
+// that it sees the CPU's accesses
+func NewObserver(mem Memory) *Observer {
+	return &Observer{Memory: mem, nextID: 1}
+}
+
+// Observe calls fn for the accesses of kinds to memory from start to end
+// inclusive, returning an ID for Remove
+func (o *Observer) Observe(start, end uint16, kinds AccessKind, fn Hook) int {
+	o.hooks = append(o.hooks, hook{o.nextID, start, end, kinds, fn})
+	o.kinds |= kinds
+	o.nextID++
+	return o.nextID - 1
+}
+
+// Remove removes the hook with id, returning false if there is none
+func (o *Observer) Remove(id int) bool {
+	for i, h := range o.hooks {
+		if h.id != id {
+			continue
+		}
+
+		o.hooks = append(o.hooks[:i:i], o.hooks[i+1:]...)
+		o.kinds = 0
+		for _, h := range o.hooks {
+			o.kinds |= h.kinds
+		}
+		return true
+	}
+	return false
+}
+
+func (o *Observer) observe(address uint16, value uint8, kind AccessKind) {
+	for _, h := range o.hooks {
+		if h.kinds&kind != 0 && address >= h.start && address <= h.end {
+			h.fn(address, value, kind)
+		}
+	}
+}
+
+func (o *Observer) Read(address uint16) (value uint8) {
+	value = o.Memory.Read(address)
+	if o.kinds&AccessRead != 0 {
+		o.observe(address, value, AccessRead)
+	}
+	return
+}
+
+func (o *Observer) Write(address uint16, value uint8) (oldValue uint8) {
+	oldValue = o.Memory.Write(address, value)
+	if o.kinds&AccessWrite != 0 {
+		o.observe(address, value, AccessWrite)
+	}
+	return
+}
+
+// Fetch is called by Execute for opcodes, see FetchMemory
+func (o *Observer) Fetch(address uint16) (value uint8) {
+	value = Fetch(o.Memory, address)
+	if o.kinds&AccessFetch != 0 {
+		o.observe(address, value, AccessFetch)
+	}
+	return
+}
+
+// Peek is not observed
+func (o *Observer) Peek(address uint16) uint8 {
+	return Peek(o.Memory, address)
+}
+
+// Poke is not observed
+func (o *Observer) Poke(address uint16, value uint8) uint8 {
+	return Poke(o.Memory, address, value)
+}
@@ -0,0 +1,108 @@
+package cpu
+
+import "testing"
+
+type observed struct {
+	address uint16
+	value   uint8
+	kind    AccessKind
+}
+
+func TestObserver(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	mem.Poke(0x0200, 0xad) // LDA $0300
+	mem.Poke(0x0201, 0x00)
+	mem.Poke(0x0202, 0x03)
+	mem.Poke(0x0203, 0x8d) // STA $0301
+	mem.Poke(0x0204, 0x01)
+	mem.Poke(0x0205, 0x03)
+	mem.Poke(0x0300, 0x42)
+
+	observer := NewObserver(mem)
+	var all, data, fetches []observed
+	observer.Observe(0x0000, 0xffff, AccessRead|AccessWrite|AccessFetch, func(address uint16, value uint8, kind AccessKind) {
+		all = append(all, observed{address, value, kind})
+	})
+	observer.Observe(0x0300, 0x03ff, AccessRead|AccessWrite, func(address uint16, value uint8, kind AccessKind) {
+		data = append(data, observed{address, value, kind})
+	})
+	id := observer.Observe(0x0000, 0xffff, AccessFetch, func(address uint16, value uint8, kind AccessKind) {
+		fetches = append(fetches, observed{address, value, kind})
+	})
+
+	cpu := NewCPU(observer)
+	cpu.Registers.PC = 0x0200
+	for i := 0; i < 2; i++ {
+		if _, err := cpu.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []observed{
+		{0x0200, 0xad, AccessFetch},
+		{0x0201, 0x00, AccessRead},
+		{0x0202, 0x03, AccessRead},
+		{0x0300, 0x42, AccessRead},
+		{0x0203, 0x8d, AccessFetch},
+		{0x0204, 0x01, AccessRead},
+		{0x0205, 0x03, AccessRead},
+		{0x0301, 0x42, AccessWrite},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("Observed %v not %v", all, expected)
+	}
+	for i := range expected {
+		if all[i] != expected[i] {
+			t.Errorf("Access %d %+v != %+v", i, all[i], expected[i])
+		}
+	}
+
+	if len(data) != 2 || data[0] != expected[3] || data[1] != expected[7] {
+		t.Errorf("Observed %v in 0x0300-0x03ff", data)
+	}
+
+	observer.Peek(0x0300)
+	observer.Poke(0x0300, 0x00)
+	if len(all) != len(expected) {
+		t.Error("Peek and Poke were observed")
+	}
+
+	if !observer.Remove(id) || observer.Remove(id) {
+		t.Error("Remove did not remove the hook once")
+	}
+	if _, err := cpu.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetches) != 2 || fetches[1] != expected[4] {
+		t.Errorf("Observed fetches %v after Remove", fetches)
+	}
+}
+
+func TestObserverCycleAccurate(t *testing.T) {
+	mem := NewBasicMemory(DEFAULT_MEMORY_SIZE)
+	mem.Poke(0x0200, 0xee) // INC $0300
+	mem.Poke(0x0201, 0x00)
+	mem.Poke(0x0202, 0x03)
+
+	observer := NewObserver(mem)
+	var kinds []AccessKind
+	observer.Observe(0x0000, 0xffff, AccessRead|AccessWrite|AccessFetch, func(address uint16, value uint8, kind AccessKind) {
+		kinds = append(kinds, kind)
+	})
+
+	cpu := NewCPU(observer, WithCycleAccuracy(nil))
+	cpu.Registers.PC = 0x0200
+	if _, err := cpu.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []AccessKind{AccessFetch, AccessRead, AccessRead, AccessRead, AccessWrite, AccessWrite}
+	if len(kinds) != len(expected) {
+		t.Fatalf("Observed %v not %v", kinds, expected)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("Access %d kind %v != %v", i, kinds[i], expected[i])
+		}
+	}
+}
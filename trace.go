@@ -0,0 +1,158 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// nestestMneumonics are the names nestest.log uses where they differ from
+// the InstructionTable's
+var nestestMneumonics = map[string]string{
+	"ISC": "ISB",
+}
+
+// Tracer writes a line in the format of nestest.log for every instruction
+// the CPU is about to execute. It reads memory with Peek so that tracing does
+// not change what the CPU sees
+type Tracer struct {
+	w   io.Writer
+	ppu func() (scanline, dot int)
+}
+
+// NewTracer returns a Tracer that writes to w
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{w: w}
+}
+
+// PPU sets where the PPU position of each line comes from. Without it the
+// position is derived from the CPU cycle count, three dots per cycle
+func (tracer *Tracer) PPU(position func() (scanline, dot int)) {
+	tracer.ppu = position
+}
+
+// Trace writes the line for the instruction at PC
+func (tracer *Tracer) Trace(cpu *CPU) (err error) {
+	_, err = io.WriteString(tracer.w, tracer.Line(cpu)+"\n")
+	return
+}
+
+// Execute traces the instruction at PC and executes it
+func (tracer *Tracer) Execute(cpu *CPU) (cycles uint16, err error) {
+	if err = tracer.Trace(cpu); err != nil {
+		return
+	}
+	return cpu.Execute()
+}
+
+// Line returns the line for the instruction at PC, e.g.
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7
+func (tracer *Tracer) Line(cpu *CPU) string {
+	reg := cpu.Registers
+	d := cpu.Disassemble(reg.PC)
+
+	var bytes []string
+	for _, value := range d.Bytes() {
+		bytes = append(bytes, fmt.Sprintf("%02X", value))
+	}
+
+	illegal := ' '
+	text := d.Text
+	if d.Instruction != nil {
+		if d.Instruction.Illegal {
+			illegal = '*'
+		}
+		text = traceInstruction(cpu, d)
+	}
+
+	var scanline, dot int
+	if tracer.ppu != nil {
+		scanline, dot = tracer.ppu()
+	} else {
+		dots := int(cpu.Cycles() * 3)
+		scanline, dot = dots/341%262, dots%341
+	}
+
+	return fmt.Sprintf("%04X  %-8s %c%-31s A:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d",
+		reg.PC, strings.Join(bytes, " "), illegal, text,
+		reg.A, reg.X, reg.Y, uint8(reg.P), reg.SP, scanline, dot, cpu.Cycles())
+}
+
+// traceInstruction formats d the way nestest.log does, with the effective
+// address and the value of memory operands
+func traceInstruction(cpu *CPU, d Disassembly) string {
+	mem := cpu.Memory
+	reg := cpu.Registers
+	inst := d.Instruction
+
+	mneumonic := inst.Mneumonic
+	if name, ok := nestestMneumonics[mneumonic]; ok {
+		mneumonic = name
+	}
+
+	low := uint8(d.Target)
+	absolute := d.Target
+	read16 := func(low, high uint16) uint16 {
+		return (uint16(Peek(mem, high)) << 8) | uint16(Peek(mem, low))
+	}
+
+	var operand string
+	switch inst.Mode {
+	case ModeAccumulator:
+		operand = "A"
+	case ModeImmediate:
+		operand = fmt.Sprintf("#$%02X", d.Operands[0])
+	case ModeZeroPage:
+		operand = fmt.Sprintf("$%02X = %02X", low, Peek(mem, uint16(low)))
+	case ModeZeroPageX, ModeZeroPageY:
+		index, name := reg.X, "X"
+		if inst.Mode == ModeZeroPageY {
+			index, name = reg.Y, "Y"
+		}
+		address := uint16(low + index)
+		operand = fmt.Sprintf("$%02X,%s @ %02X = %02X", low, name, address, Peek(mem, address))
+	case ModeAbsolute:
+		if inst.OpCode == 0x4c || inst.OpCode == 0x20 {
+			operand = fmt.Sprintf("$%04X", absolute)
+		} else {
+			operand = fmt.Sprintf("$%04X = %02X", absolute, Peek(mem, absolute))
+		}
+	case ModeAbsoluteX, ModeAbsoluteY:
+		index, name := reg.X, "X"
+		if inst.Mode == ModeAbsoluteY {
+			index, name = reg.Y, "Y"
+		}
+		address := absolute + uint16(index)
+		operand = fmt.Sprintf("$%04X,%s @ %04X = %02X", absolute, name, address, Peek(mem, address))
+	case ModeIndirect:
+		var pointer uint16
+		if cpu.variant == CMOS65C02 {
+			pointer = read16(absolute, absolute+1)
+		} else {
+			pointer = read16(absolute, (absolute&0xff00)|uint16(uint8(absolute)+1))
+		}
+		operand = fmt.Sprintf("($%04X) = %04X", absolute, pointer)
+	case ModeIndexedIndirect:
+		address := low + reg.X
+		pointer := read16(uint16(address), uint16(address+1))
+		operand = fmt.Sprintf("($%02X,X) @ %02X = %04X = %02X", low, address, pointer, Peek(mem, pointer))
+	case ModeIndirectIndexed:
+		pointer := read16(uint16(low), uint16(low+1))
+		address := pointer + uint16(reg.Y)
+		operand = fmt.Sprintf("($%02X),Y = %04X @ %04X = %02X", low, pointer, address, Peek(mem, address))
+	case ModeRelative:
+		operand = fmt.Sprintf("$%04X", d.Target)
+	case ModeZeroPageIndirect:
+		pointer := read16(uint16(low), uint16(low+1))
+		operand = fmt.Sprintf("($%02X) = %04X = %02X", low, pointer, Peek(mem, pointer))
+	case ModeAbsoluteIndexedIndirect:
+		address := absolute + uint16(reg.X)
+		operand = fmt.Sprintf("($%04X,X) = %04X", absolute, read16(address, address+1))
+	}
+
+	if operand == "" {
+		return mneumonic
+	}
+	return mneumonic + " " + operand
+}
@@ -0,0 +1,75 @@
+package cpu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	cpu := NewCPU(NewBasicMemory(DEFAULT_MEMORY_SIZE), WithVariant(Ricoh2A03), WithIllegalOpcodes())
+	cpu.PowerOn()
+	cpu.Registers.PC = 0xc000
+
+	for address, value := range map[uint16]uint8{
+		0xc000: 0x4c, 0xc001: 0xf5, 0xc002: 0xc5,
+		0xc5f5: 0xa2, 0xc5f6: 0x00,
+		0xc5f7: 0x86, 0xc5f8: 0x00,
+		0xc5f9: 0x04, 0xc5fa: 0xa9,
+		0xc5fb: 0xb1, 0xc5fc: 0x89,
+		0x0089: 0x00, 0x008a: 0x03, 0x0300: 0x89,
+	} {
+		cpu.Memory.Write(address, value)
+	}
+
+	var out bytes.Buffer
+	tracer := NewTracer(&out)
+	for i := 0; i < 5; i++ {
+		if _, err := tracer.Execute(cpu); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+		"C5F5  A2 00     LDX #$00                        A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 30 CYC:10",
+		"C5F7  86 00     STX $00 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 36 CYC:12",
+		"C5F9  04 A9    *NOP $A9 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 45 CYC:15",
+		"C5FB  B1 89     LDA ($89),Y = 0300 @ 0300 = 89  A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 54 CYC:18",
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Traced %v lines not %v", len(lines), len(expected))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %v\n%v\n!=\n%v", i+1, lines[i], expected[i])
+		}
+	}
+}
+
+// readCountingMemory counts the reads that could have side effects
+type readCountingMemory struct {
+	*BasicMemory
+	reads int
+}
+
+func (mem *readCountingMemory) Read(address uint16) uint8 {
+	mem.reads++
+	return mem.BasicMemory.Read(address)
+}
+
+func TestTracerPeeks(t *testing.T) {
+	mem := &readCountingMemory{BasicMemory: NewBasicMemory(DEFAULT_MEMORY_SIZE)}
+	mem.Poke(0x0200, 0xb1) // LDA ($89),Y
+	mem.Poke(0x0201, 0x89)
+
+	cpu := NewCPU(mem)
+	cpu.Registers.PC = 0x0200
+	NewTracer(nil).Line(cpu)
+	cpu.Disassemble(0x0200)
+
+	if mem.reads != 0 {
+		t.Errorf("Tracing read memory %d times", mem.reads)
+	}
+}